@@ -3,24 +3,72 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
-	"os"
 	"reflect"
+	"time"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/prelude"
+	"monkey-playground-backend/token"
+	"monkey-playground-backend/vm"
+)
 
-	"github.com/NavrajBal/monkey-lang/ast"
-	"github.com/NavrajBal/monkey-lang/compiler"
-	"github.com/NavrajBal/monkey-lang/evaluator"
-	"github.com/NavrajBal/monkey-lang/lexer"
-	"github.com/NavrajBal/monkey-lang/object"
-	"github.com/NavrajBal/monkey-lang/parser"
-	"github.com/NavrajBal/monkey-lang/token"
-	"github.com/NavrajBal/monkey-lang/vm"
+// The budget of every program the handlers run, macros and prelude
+// included. A run also stops when its request's context is done, e.g.
+// because the client went away.
+var (
+	MaxSteps   = 10_000_000
+	RunTimeout = 5 * time.Second
 )
 
+// limits returns the budget for a run serving r
+func limits(r *http.Request) object.Limits {
+	return object.Budget(r.Context(), MaxSteps, RunTimeout)
+}
+
 // Request/Response types
 type CodeRequest struct {
 	Code string `json:"code"`
+	// Files optionally holds a multi-file program keyed by path, e.g.
+	// "lib/math.monkey"; Entry names the main file and defaults to
+	// main.monkey
+	Files map[string]string `json:"files,omitempty"`
+	Entry string            `json:"entry,omitempty"`
+	// Prelude names the prelude modules to load before the code, such as
+	// "core" and "math"; PreludeTraces keeps their calls in error traces
+	Prelude       []string `json:"prelude,omitempty"`
+	PreludeTraces bool     `json:"preludeTraces,omitempty"`
+}
+
+// Source returns the code of the main program (see modules.Program) and a
+// module loader over the request's file set
+func (req *CodeRequest) Source() (string, *modules.Loader, error) {
+	files, err := modules.NewFiles(req.Files)
+	if err != nil {
+		return "", nil, err
+	}
+	code, err := modules.Program(req.Code, files, req.Entry)
+	if err != nil {
+		return "", nil, err
+	}
+	return code, modules.NewLoader(files, req.Entry), nil
+}
+
+// Visible returns err as the response reports it, without the calls of
+// prelude functions unless the request asks for them
+func (req *CodeRequest) Visible(err error) error {
+	if located, ok := err.(*object.Error); ok && !req.PreludeTraces {
+		return object.HideFrames(located)
+	}
+	return err
 }
 
 type TokenizeResponse struct {
@@ -32,6 +80,8 @@ type TokenInfo struct {
 	Type     string `json:"type"`
 	Literal  string `json:"literal"`
 	Position int    `json:"position"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
 }
 
 type ParseResponse struct {
@@ -39,22 +89,49 @@ type ParseResponse struct {
 	Error string      `json:"error,omitempty"`
 }
 
+// CompileResponse is the bytecode listing shared with the WASM compile
+// function: raw bytes, the constant pool and a decoded disassembly
 type CompileResponse struct {
-	Bytecode    []byte        `json:"bytecode"`
-	Constants   []interface{} `json:"constants"`
-	Instructions string       `json:"instructions"`
-	Error       string        `json:"error,omitempty"`
+	compiler.Listing
+	Error string `json:"error,omitempty"`
+	ErrorLocation
 }
 
+// ExecuteResponse reports a run. Result is the value's Inspect string and
+// Value the same value as a typed tree (see object.Describe).
 type ExecuteResponse struct {
-	Result string `json:"result"`
-	Output string `json:"output,omitempty"`
-	Error  string `json:"error,omitempty"`
+	Result      string               `json:"result"`
+	Value       map[string]any       `json:"value,omitempty"`
+	Output      string               `json:"output,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	ErrorLocation
+	Diagnostics []modules.Diagnostic `json:"diagnostics,omitempty"`
 }
 
 type ReplResponse struct {
-	Result string `json:"result"`
-	Error  string `json:"error,omitempty"`
+	Result      string               `json:"result"`
+	Value       map[string]any       `json:"value,omitempty"`
+	Output      string               `json:"output,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	ErrorLocation
+	Diagnostics []modules.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// ErrorLocation is where a compile or runtime error happened (1-based) and
+// the Monkey calls active at the time, innermost first
+type ErrorLocation struct {
+	Line   int                  `json:"line,omitempty"`
+	Column int                  `json:"column,omitempty"`
+	Stack  []object.StackFrame  `json:"stack,omitempty"`
+}
+
+// Locate returns the position and stack trace of err, if it has one
+func Locate(err error) ErrorLocation {
+	var located *object.Error
+	if !errors.As(err, &located) {
+		return ErrorLocation{}
+	}
+	return ErrorLocation{Line: located.Line, Column: located.Column, Stack: located.Stack}
 }
 
 // TokenizeHandler converts code to tokens
@@ -70,7 +147,19 @@ func TokenizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	l := lexer.New(req.Code)
+	var response TokenizeResponse
+	if code, _, err := req.Source(); err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Tokens = Tokenize(code)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Tokenize runs the lexer over code and collects every token before EOF
+func Tokenize(code string) []TokenInfo {
+	l := lexer.New(code)
 	var tokens []TokenInfo
 	position := 0
 
@@ -84,13 +173,13 @@ func TokenizeHandler(w http.ResponseWriter, r *http.Request) {
 			Type:     string(tok.Type),
 			Literal:  tok.Literal,
 			Position: position,
+			Line:     tok.Line,
+			Column:   tok.Column,
 		})
 		position += len(tok.Literal)
 	}
 
-	response := TokenizeResponse{Tokens: tokens}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return tokens
 }
 
 // ParseHandler converts code to AST
@@ -106,7 +195,15 @@ func ParseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	l := lexer.New(req.Code)
+	code, _, err := req.Source()
+	if err != nil {
+		response := ParseResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -138,7 +235,15 @@ func CompileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	l := lexer.New(req.Code)
+	code, _, err := req.Source()
+	if err != nil {
+		response := CompileResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -149,28 +254,34 @@ func CompileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comp := compiler.New()
-	if err := comp.Compile(program); err != nil {
-		response := CompileResponse{Error: err.Error()}
+	macros := evaluator.NewEnvironment(nil, io.Discard)
+	evaluator.SetLimits(macros, limits(r))
+	if err := evaluator.Expand(program, macros); err != nil {
+		response := CompileResponse{Error: err.Error(), ErrorLocation: Locate(err)}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	bytecode := comp.Bytecode()
-	
-	// Convert constants to JSON-serializable format
-	constants := make([]interface{}, len(bytecode.Constants))
-	for i, c := range bytecode.Constants {
-		constants[i] = c.Inspect()
+	// The listing's constants start with the prelude's
+	pre, err := prelude.Load(req.Prelude)
+	if err != nil {
+		response := CompileResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	response := CompileResponse{
-		Bytecode:     bytecode.Instructions,
-		Constants:    constants,
-		Instructions: bytecode.Instructions.String(),
+	comp := pre.Compiler()
+	if err := comp.Compile(program); err != nil {
+		response := CompileResponse{Error: err.Error(), ErrorLocation: Locate(err)}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
 	}
-	
+
+	response := CompileResponse{Listing: compiler.NewListing(comp.Bytecode())}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -188,7 +299,15 @@ func ExecuteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	l := lexer.New(req.Code)
+	code, loader, err := req.Source()
+	if err != nil {
+		response := ExecuteResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -199,38 +318,45 @@ func ExecuteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comp := compiler.New()
-	if err := comp.Compile(program); err != nil {
-		response := ExecuteResponse{Error: err.Error()}
+	// Expand macros before compiling; the VM then runs the expanded
+	// program, capturing puts and resolving import() against the
+	// request's files
+	var buf bytes.Buffer
+	budget := limits(r)
+	macros := evaluator.NewEnvironment(nil, &buf)
+	evaluator.SetLimits(macros, budget)
+	if err := evaluator.Expand(program, macros); err != nil {
+		response := ExecuteResponse{Error: err.Error(), ErrorLocation: Locate(err), Output: buf.String()}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// Capture stdout during execution
-	oldStdout := os.Stdout
-	pipeR, pipeW, _ := os.Pipe()
-	os.Stdout = pipeW
-
-	// Buffer to capture output
-	outputChan := make(chan string)
-	go func() {
-		var buf bytes.Buffer
-		io.Copy(&buf, pipeR)
-		outputChan <- buf.String()
-	}()
+	pre, err := prelude.Load(req.Prelude)
+	if err != nil {
+		response := ExecuteResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
-	// Run the VM
-	machine := vm.New(comp.Bytecode())
-	err := machine.Run()
+	comp := pre.Compiler()
+	if err := comp.Compile(program); err != nil {
+		response := ExecuteResponse{Error: err.Error(), ErrorLocation: Locate(err)}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
-	// Restore stdout
-	pipeW.Close()
-	os.Stdout = oldStdout
-	output := <-outputChan
+	machine := vm.NewWithGlobalsStore(comp.Bytecode(), pre.Globals())
+	machine.SetOutput(&buf)
+	machine.EnableModules(loader, comp.SymbolTable())
+	machine.SetLimits(budget)
+	err = req.Visible(machine.Run())
+	output := buf.String()
 
 	if err != nil {
-		response := ExecuteResponse{Error: err.Error(), Output: output}
+		response := ExecuteResponse{Error: err.Error(), ErrorLocation: Locate(err), Output: output, Diagnostics: loader.Diagnostics()}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
@@ -239,7 +365,7 @@ func ExecuteHandler(w http.ResponseWriter, r *http.Request) {
 	lastPopped := machine.LastPoppedStackElem()
 	result := lastPopped.Inspect()
 
-	response := ExecuteResponse{Result: result, Output: output}
+	response := ExecuteResponse{Result: result, Value: object.Describe(lastPopped), Output: output, Diagnostics: loader.Diagnostics()}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -257,8 +383,16 @@ func ReplHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	code, loader, err := req.Source()
+	if err != nil {
+		response := ReplResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	// For now, just use the evaluator for REPL-like behavior
-	l := lexer.New(req.Code)
+	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -269,21 +403,109 @@ func ReplHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	env := object.NewEnvironment()
+	pre, err := prelude.Load(req.Prelude)
+	if err != nil {
+		response := ReplResponse{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var buf bytes.Buffer
+	env := pre.Environment(loader, &buf)
+	evaluator.SetLimits(env, limits(r))
 	result := evaluator.Eval(program, env)
 
+	if err, ok := result.(*object.Error); ok {
+		located := req.Visible(err)
+		response := ReplResponse{Error: err.Message, ErrorLocation: Locate(located), Output: buf.String(), Diagnostics: loader.Diagnostics()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	if result != nil {
-		response := ReplResponse{Result: result.Inspect()}
+		response := ReplResponse{Result: result.Inspect(), Value: object.Describe(result), Output: buf.String(), Diagnostics: loader.Diagnostics()}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	response := ReplResponse{Result: "null"}
+	response := ReplResponse{Result: "null", Value: object.Describe(nil), Output: buf.String(), Diagnostics: loader.Diagnostics()}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ExpandResponse shows a program before and after macro expansion, as
+// ASTs in the /api/parse format and as the expanded code
+type ExpandResponse struct {
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+	Expanded string      `json:"expanded,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	ErrorLocation
+}
+
+// ExpandHandler expands the macros in code
+func ExpandHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var response ExpandResponse
+	if code, _, err := req.Source(); err != nil {
+		response.Error = err.Error()
+	} else {
+		response = Expand(code, limits(r))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// Expand parses code and expands its macros. Macro bodies run on the
+// evaluator within limits and without module files, and their puts output
+// is discarded.
+func Expand(code string, limits object.Limits) ExpandResponse {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return ExpandResponse{Error: p.Errors()[0]}
+	}
+
+	response := ExpandResponse{Before: ConvertASTToJSON(program)}
+	env := evaluator.NewEnvironment(nil, io.Discard)
+	evaluator.SetLimits(env, limits)
+	if err := evaluator.Expand(program, env); err != nil {
+		response.Error, response.ErrorLocation = err.Error(), Locate(err)
+		return response
+	}
+	response.After = ConvertASTToJSON(program)
+	response.Expanded = program.String()
+	return response
+}
+
+// BuiltinsResponse lists the builtin functions (see
+// object.DescribeBuiltins) for editor completion and documentation
+type BuiltinsResponse struct {
+	Builtins []any `json:"builtins"`
+}
+
+// BuiltinsHandler lists the builtin functions with their metadata
+func BuiltinsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, BuiltinsResponse{Builtins: object.DescribeBuiltins()})
+}
+
 // ConvertASTToJSON converts AST nodes to JSON with type information
 func ConvertASTToJSON(node ast.Node) map[string]interface{} {
 	result := make(map[string]interface{})
@@ -346,6 +568,9 @@ func ConvertASTToJSON(node ast.Node) map[string]interface{} {
 			"Literal": n.Token.Literal,
 		}
 		result["Value"] = n.Value
+		if n.Big != nil {
+			result["Value"] = n.Big.String()
+		}
 		
 	case *ast.Boolean:
 		result["Token"] = map[string]interface{}{
@@ -420,6 +645,20 @@ func ConvertASTToJSON(node ast.Node) map[string]interface{} {
 			result["Body"] = ConvertASTToJSON(n.Body)
 		}
 		
+	case *ast.MacroLiteral:
+		result["Token"] = map[string]interface{}{
+			"Type":    string(n.Token.Type),
+			"Literal": n.Token.Literal,
+		}
+		var parameters []map[string]interface{}
+		for _, param := range n.Parameters {
+			parameters = append(parameters, ConvertASTToJSON(param))
+		}
+		result["Parameters"] = parameters
+		if n.Body != nil {
+			result["Body"] = ConvertASTToJSON(n.Body)
+		}
+		
 	case *ast.CallExpression:
 		if n.Function != nil {
 			result["Function"] = ConvertASTToJSON(n.Function)
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post sends body to handler and decodes the JSON response into response
+func post(t *testing.T, handler func(w *httptest.ResponseRecorder, body string), body string, response any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, body)
+	if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+		t.Fatalf("%s: %v", body, err)
+	}
+}
+
+func TestRequestSources(t *testing.T) {
+	handlers := map[string]func(w *httptest.ResponseRecorder, body string){
+		"execute": func(w *httptest.ResponseRecorder, body string) {
+			ExecuteHandler(w, httptest.NewRequest("POST", "/api/execute", strings.NewReader(body)))
+		},
+		"repl": func(w *httptest.ResponseRecorder, body string) {
+			ReplHandler(w, httptest.NewRequest("POST", "/api/repl", strings.NewReader(body)))
+		},
+	}
+	tests := []struct {
+		body   string
+		result string
+		output string
+		err    string
+	}{
+		{`{"code": "puts(1)"}`, "null", "1\n", ""},
+		{`{"files": {"main.monkey": "puts(2); 3"}}`, "3", "2\n", ""},
+		{`{"code": "import(\"lib/a\")[\"x\"]", "files": {"lib/a.monkey": "let x = 4;"}}`, "4", "", ""},
+		{`{"code": "1", "files": {"main.monkey": "2"}}`, "", "", `both code and files["main.monkey"] hold the main program; send it once`},
+		{`{"files": {"main.monkey": "1", "./main": "2"}}`, "", "", `files["./main"] and files["main.monkey"] are both main.monkey; send it once`},
+	}
+
+	for name, handler := range handlers {
+		for _, tt := range tests {
+			var response ExecuteResponse
+			post(t, handler, tt.body, &response)
+			if response.Result != tt.result || response.Output != tt.output || response.Error != tt.err {
+				t.Errorf("%s %s: got %+v, want result %q, output %q, error %q", name, tt.body, response, tt.result, tt.output, tt.err)
+			}
+		}
+	}
+}
+
+func TestReplKeepsDiagnostics(t *testing.T) {
+	var response ReplResponse
+	body := `{"code": "let r = try(fn() { import(\"lib/bad\") }, fn(e) { puts(\"x\") })", "files": {"lib/bad.monkey": "let = 1;"}}`
+	post(t, func(w *httptest.ResponseRecorder, body string) {
+		ReplHandler(w, httptest.NewRequest("POST", "/api/repl", strings.NewReader(body)))
+	}, body, &response)
+	if response.Result != "null" || response.Output != "x\n" || len(response.Diagnostics) != 1 || response.Diagnostics[0].File != "lib/bad.monkey" {
+		t.Errorf("got %+v", response)
+	}
+}
+
+func TestExpandFiles(t *testing.T) {
+	var response ExpandResponse
+	body := `{"files": {"main.monkey": "let twice = macro(x) { quote(unquote(x) * 2) }; twice(3)"}}`
+	post(t, func(w *httptest.ResponseRecorder, body string) {
+		ExpandHandler(w, httptest.NewRequest("POST", "/api/expand", strings.NewReader(body)))
+	}, body, &response)
+	if response.Error != "" || response.Expanded != "(3 * 2)" {
+		t.Errorf("got expanded %q, error %q", response.Expanded, response.Error)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	body := `{"files": {"lib/a.monkey": "let x = 4;"}, "entry": "lib/a"}`
+	var parsed ParseResponse
+	post(t, func(w *httptest.ResponseRecorder, body string) {
+		ParseHandler(w, httptest.NewRequest("POST", "/api/parse", strings.NewReader(body)))
+	}, body, &parsed)
+	if parsed.Error != "" || parsed.AST == nil {
+		t.Errorf("parse: got %+v", parsed)
+	}
+	var tokens TokenizeResponse
+	post(t, func(w *httptest.ResponseRecorder, body string) {
+		TokenizeHandler(w, httptest.NewRequest("POST", "/api/tokenize", strings.NewReader(body)))
+	}, body, &tokens)
+	if tokens.Error != "" || len(tokens.Tokens) != 5 || tokens.Tokens[1].Literal != "x" {
+		t.Errorf("tokenize: got %+v", tokens)
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	saved := MaxSteps
+	MaxSteps = 100_000
+	t.Cleanup(func() { MaxSteps = saved })
+
+	handlers := map[string]func(w *httptest.ResponseRecorder, body string){
+		"execute": func(w *httptest.ResponseRecorder, body string) {
+			ExecuteHandler(w, httptest.NewRequest("POST", "/api/execute", strings.NewReader(body)))
+		},
+		"repl": func(w *httptest.ResponseRecorder, body string) {
+			ReplHandler(w, httptest.NewRequest("POST", "/api/repl", strings.NewReader(body)))
+		},
+	}
+	for name, handler := range handlers {
+		var response ExecuteResponse
+		post(t, handler, `{"code": "let f = fn() { f() }; f()"}`, &response)
+		if !strings.HasPrefix(response.Error, "budget exceeded: more than 100000 steps") {
+			t.Errorf("%s: got %+v, want a budget error", name, response)
+		}
+	}
+
+	// A request whose client has gone away stops at the next check
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	ExecuteHandler(rec, httptest.NewRequest("POST", "/api/execute", strings.NewReader(`{"code": "let f = fn() { f() }; f()"}`)).WithContext(ctx))
+	var response ExecuteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Error != "execution cancelled" {
+		t.Errorf("got %+v, %v, want a cancelled run", response, err)
+	}
+}
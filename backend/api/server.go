@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Route describes a single endpoint served by the playground API
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+}
+
+// Routes lists every endpoint exposed by NewServer
+func Routes() []Route {
+	return []Route{
+		{"GET", "/health", HealthHandler},
+		{"POST", "/api/tokenize", TokenizeHandler},
+		{"POST", "/api/parse", ParseHandler},
+		{"POST", "/api/compile", CompileHandler},
+		{"POST", "/api/execute", ExecuteHandler},
+		{"POST", "/api/repl", ReplHandler},
+		{"POST", "/api/expand", ExpandHandler},
+		{"GET", "/api/builtins", BuiltinsHandler},
+		{"GET, POST", "/api/snippets", SnippetsHandler},
+		{"GET", "/api/snippets/", SnippetHandler},
+	}
+}
+
+// NewServer builds the playground HTTP API with CORS applied
+func NewServer() http.Handler {
+	return NewServerWithFrontend(nil)
+}
+
+// NewServerWithFrontend builds the API and, when frontend is not nil,
+// serves it for every path the API does not handle
+func NewServerWithFrontend(frontend http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range Routes() {
+		mux.HandleFunc(route.Path, route.Handler)
+	}
+	if frontend != nil {
+		mux.Handle("/", frontend)
+	}
+	return corsHandler(mux)
+}
+
+// HealthHandler reports that the server is up
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// corsHandler allows the frontend dev server to call the API
+func corsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
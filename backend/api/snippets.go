@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"monkey-playground-backend/snippets"
+)
+
+// snippetService backs /api/snippets; main swaps in a FileStore when a
+// snippet directory is configured
+var snippetService = snippets.NewService(snippets.NewMemoryStore())
+
+// SetSnippetStore replaces the store used by the snippet endpoints
+func SetSnippetStore(store snippets.Store) {
+	snippetService = snippets.NewService(store)
+}
+
+type SnippetRequest struct {
+	Code   string           `json:"code"`
+	Engine string           `json:"engine,omitempty"`
+	Result *snippets.Result `json:"result,omitempty"`
+}
+
+type SnippetResponse struct {
+	*snippets.Snippet
+	Created bool   `json:"created,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type SnippetListResponse struct {
+	Snippets []*snippets.Snippet `json:"snippets"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// SnippetsHandler creates snippets (POST) and lists recent ones (GET)
+func SnippetsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		createSnippet(w, r)
+	case "GET":
+		listSnippets(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SnippetHandler fetches a single snippet by its short ID
+func SnippetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/snippets/")
+	if id == "" || strings.Contains(id, "/") {
+		writeJSON(w, http.StatusNotFound, SnippetResponse{Error: snippets.ErrNotFound.Error()})
+		return
+	}
+
+	snippet, err := snippetService.Get(id)
+	if err != nil {
+		writeJSON(w, snippetErrorStatus(err), SnippetResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SnippetResponse{Snippet: snippet})
+}
+
+func createSnippet(w http.ResponseWriter, r *http.Request) {
+	// Allow headroom over the code limit for the engine and saved result
+	r.Body = http.MaxBytesReader(w, r.Body, int64(snippetService.MaxCodeSize+4*snippetService.MaxOutputSize))
+
+	var req SnippetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, SnippetResponse{Error: snippets.ErrCodeTooLarge.Error()})
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	snippet, created, err := snippetService.Create(req.Code, req.Engine, req.Result)
+	if err != nil {
+		writeJSON(w, snippetErrorStatus(err), SnippetResponse{Error: err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, SnippetResponse{Snippet: snippet, Created: created})
+}
+
+func listSnippets(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	recent, err := snippetService.Recent(limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, SnippetListResponse{Snippets: []*snippets.Snippet{}, Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SnippetListResponse{Snippets: recent})
+}
+
+func snippetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, snippets.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, snippets.ErrEmptyCode), errors.Is(err, snippets.ErrBadEngine):
+		return http.StatusBadRequest
+	case errors.Is(err, snippets.ErrCodeTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
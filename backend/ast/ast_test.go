@@ -0,0 +1,42 @@
+package ast
+
+import (
+	"testing"
+
+	"monkey-playground-backend/token"
+)
+
+func TestString(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name: &Identifier{Token: token.Token{Type: token.IDENT, Literal: "myVar"}, Value: "myVar"},
+				Value: &Identifier{Token: token.Token{Type: token.IDENT, Literal: "anotherVar"}, Value: "anotherVar"},
+			},
+		},
+	}
+	if program.String() != "let myVar = anotherVar;" {
+		t.Errorf("program.String() wrong, got=%q", program.String())
+	}
+}
+
+func TestPosition(t *testing.T) {
+	callee := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "f", Line: 3, Column: 7}, Value: "f"}
+	call := &CallExpression{Token: token.Token{Type: token.LPAREN, Literal: "(", Line: 3, Column: 8}, Function: callee}
+	tests := []struct {
+		node         Node
+		line, column int
+	}{
+		{callee, 3, 7},
+		{call, 3, 7},
+		{&ExpressionStatement{Token: call.Token, Expression: call}, 3, 7},
+		{&ExpressionStatement{Token: token.Token{Line: 2, Column: 1}}, 2, 1},
+		{&Program{}, 0, 0},
+	}
+	for i, tt := range tests {
+		if line, column := Position(tt.node); line != tt.line || column != tt.column {
+			t.Errorf("tests[%d] - %T at %d:%d, want %d:%d", i, tt.node, line, column, tt.line, tt.column)
+		}
+	}
+}
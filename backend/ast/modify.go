@@ -0,0 +1,110 @@
+package ast
+
+// ModifierFunc returns the node to put in place of node
+type ModifierFunc func(node Node) Node
+
+// Modify returns node with every node in it replaced by modifier(node),
+// children before their parents. Nodes with children are copied rather
+// than changed, so the original tree can be modified again, as a macro's
+// body is on every expansion. Function parameters, let names and macro
+// literals are left as they are.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		copied := *n
+		copied.Statements = modifyStatements(n.Statements, modifier)
+		node = &copied
+	case *ExpressionStatement:
+		copied := *n
+		copied.Expression = modifyExpression(n.Expression, modifier)
+		node = &copied
+	case *BlockStatement:
+		copied := *n
+		copied.Statements = modifyStatements(n.Statements, modifier)
+		node = &copied
+	case *ReturnStatement:
+		copied := *n
+		copied.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+		node = &copied
+	case *LetStatement:
+		copied := *n
+		copied.Value = modifyExpression(n.Value, modifier)
+		node = &copied
+	case *PrefixExpression:
+		copied := *n
+		copied.Right = modifyExpression(n.Right, modifier)
+		node = &copied
+	case *InfixExpression:
+		copied := *n
+		copied.Left = modifyExpression(n.Left, modifier)
+		copied.Right = modifyExpression(n.Right, modifier)
+		node = &copied
+	case *IndexExpression:
+		copied := *n
+		copied.Left = modifyExpression(n.Left, modifier)
+		copied.Index = modifyExpression(n.Index, modifier)
+		node = &copied
+	case *IfExpression:
+		copied := *n
+		copied.Condition = modifyExpression(n.Condition, modifier)
+		copied.Consequence = modifyBlock(n.Consequence, modifier)
+		copied.Alternative = modifyBlock(n.Alternative, modifier)
+		node = &copied
+	case *FunctionLiteral:
+		copied := *n
+		copied.Body = modifyBlock(n.Body, modifier)
+		node = &copied
+	case *CallExpression:
+		copied := *n
+		copied.Function = modifyExpression(n.Function, modifier)
+		copied.Arguments = modifyExpressions(n.Arguments, modifier)
+		node = &copied
+	case *ArrayLiteral:
+		copied := *n
+		copied.Elements = modifyExpressions(n.Elements, modifier)
+		node = &copied
+	case *HashLiteral:
+		copied := *n
+		copied.Pairs = make([]HashLiteralPair, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			copied.Pairs[i] = HashLiteralPair{Key: modifyExpression(pair.Key, modifier), Value: modifyExpression(pair.Value, modifier)}
+		}
+		node = &copied
+	}
+	return modifier(node)
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	modified := make([]Statement, len(statements))
+	for i, s := range statements {
+		modified[i], _ = Modify(s, modifier).(Statement)
+	}
+	return modified
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	if expressions == nil {
+		return nil
+	}
+	modified := make([]Expression, len(expressions))
+	for i, e := range expressions {
+		modified[i] = modifyExpression(e, modifier)
+	}
+	return modified
+}
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+	modified, _ := Modify(e, modifier).(Expression)
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified, _ := Modify(block, modifier).(*BlockStatement)
+	return modified
+}
@@ -0,0 +1,50 @@
+package ast
+
+import "monkey-playground-backend/token"
+
+// Position returns the line and column of node's token, or 0, 0 for nodes
+// without one. A call is located at its callee, so errors and traces point
+// at f in f(x); an expression statement at its expression.
+func Position(node Node) (line, column int) {
+	var tok token.Token
+	switch n := node.(type) {
+	case *CallExpression:
+		return Position(n.Function)
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			return Position(n.Expression)
+		}
+		tok = n.Token
+	case *LetStatement:
+		tok = n.Token
+	case *ReturnStatement:
+		tok = n.Token
+	case *BlockStatement:
+		tok = n.Token
+	case *Identifier:
+		tok = n.Token
+	case *Boolean:
+		tok = n.Token
+	case *IntegerLiteral:
+		tok = n.Token
+	case *StringLiteral:
+		tok = n.Token
+	case *PrefixExpression:
+		tok = n.Token
+	case *InfixExpression:
+		tok = n.Token
+	case *IfExpression:
+		tok = n.Token
+	case *FunctionLiteral:
+		tok = n.Token
+	case *ArrayLiteral:
+		tok = n.Token
+	case *IndexExpression:
+		tok = n.Token
+	case *HashLiteral:
+		tok = n.Token
+	case *MacroLiteral:
+		tok = n.Token
+	}
+	return tok.Line, tok.Column
+}
@@ -0,0 +1,32 @@
+#!/bin/bash
+# Builds a single monkey-playground binary with the frontend embedded.
+set -e
+
+cd "$(dirname "$0")"
+BACKEND_DIR="$(pwd)"
+FRONTEND_DIR="$BACKEND_DIR/../frontend"
+
+echo "📥 Installing frontend dependencies..."
+(cd "$FRONTEND_DIR" && npm ci)
+
+# wasm_exec.js must come from the Go toolchain that builds the module
+echo "🐒 Building WASM module..."
+(cd "$FRONTEND_DIR" && npm run build-wasm)
+WASM_EXEC="$(go env GOROOT)/lib/wasm/wasm_exec.js"
+if [ ! -f "$WASM_EXEC" ]; then
+    # Go 1.23 and older keep it under misc/
+    WASM_EXEC="$(go env GOROOT)/misc/wasm/wasm_exec.js"
+fi
+cp "$WASM_EXEC" "$FRONTEND_DIR/public/wasm_exec.js"
+
+echo "📦 Building frontend..."
+(cd "$FRONTEND_DIR" && npm run build)
+
+echo "📁 Copying frontend build into web/dist..."
+rm -rf web/dist
+cp -r "$FRONTEND_DIR/dist" web/dist
+
+echo "🔧 Building binary..."
+go build -tags embedui -o monkey-playground .
+
+echo "✅ Built ./monkey-playground — run it and open http://localhost:8080/"
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"monkey-playground-backend/api"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/web"
+)
+
+func runTokenize(args []string) int {
+	fs := flag.NewFlagSet("tokenize", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print tokens as JSON (same schema as /api/tokenize)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	code, err := readSource(fs.Args())
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	tokens := api.Tokenize(code)
+	if *asJSON {
+		return printJSON(api.TokenizeResponse{Tokens: tokens})
+	}
+
+	status := exitOK
+	for _, tok := range tokens {
+		fmt.Fprintf(stdout, "%5d  %-10s %s\n", tok.Position, tok.Type, tok.Literal)
+		if tok.Type == "ILLEGAL" {
+			status = exitError
+		}
+	}
+	return status
+}
+
+func runParse(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "json", "output format: json or tree")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *format != "json" && *format != "tree" {
+		return fail(exitUsage, "unknown format %q (want json or tree)", *format)
+	}
+
+	code, err := readSource(fs.Args())
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	program, err := parse(code)
+	if err != nil {
+		return fail(exitError, "parse error:\n%v", err)
+	}
+
+	astData := api.ConvertASTToJSON(program)
+	if *format == "json" {
+		return printJSON(astData)
+	}
+
+	printTree(stdout, astData, "", 0)
+	return exitOK
+}
+
+func runCompile(args []string) int {
+	fs := flag.NewFlagSet("compile", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the listing as JSON (same schema as /api/compile)")
+	limits := budgetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	code, err := readSource(fs.Args())
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	program, err := parse(code)
+	if err != nil {
+		return fail(exitError, "parse error:\n%v", err)
+	}
+
+	macros := evaluator.NewEnvironment(nil, io.Discard)
+	evaluator.SetLimits(macros, limits.limits())
+	if err := evaluator.Expand(program, macros); err != nil {
+		return fail(exitError, "macro error: %v", err)
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return fail(exitError, "compile error: %v", err)
+	}
+
+	bytecode := comp.Bytecode()
+	if *asJSON {
+		return printJSON(compiler.NewListing(bytecode))
+	}
+
+	fmt.Fprintln(stdout, "Constants:")
+	for i, c := range bytecode.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fmt.Fprintf(stdout, "  %04d %s (locals=%d, params=%d)\n", i, c.Type(), fn.NumLocals, fn.NumParameters)
+			fmt.Fprint(stdout, indent(fn.Instructions.String(), "         "))
+			continue
+		}
+		fmt.Fprintf(stdout, "  %04d %s %s\n", i, c.Type(), c.Inspect())
+	}
+
+	fmt.Fprintln(stdout, "Instructions:")
+	fmt.Fprint(stdout, indent(bytecode.Instructions.String(), "  "))
+	return exitOK
+}
+
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	engineName := fs.String("engine", "vm", "execution engine: vm or eval")
+	quiet := fs.Bool("q", false, "do not print the value of the last expression")
+	preludeNames := fs.String("prelude", "", "comma-separated prelude modules to load, e.g. core,math")
+	limits := budgetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	eng, err := newEngine(*engineName, fileLoader(fs.Args()), preludeFlag(*preludeNames), *limits)
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	code, err := readSource(fs.Args())
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	program, err := parse(code)
+	if err != nil {
+		return fail(exitError, "parse error:\n%v", err)
+	}
+
+	result, err := eng.Run(program)
+	if err != nil {
+		return fail(exitError, "runtime error: %s", describe(err))
+	}
+
+	if !*quiet && result != nil {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+	return exitOK
+}
+
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	port := fs.String("port", envOr("PORT", "8080"), "port to listen on")
+	snippetDir := fs.String("snippets", os.Getenv("SNIPPET_DIR"), "directory for shared snippets (in-memory when empty)")
+	frontendDir := fs.String("frontend", "", "serve a frontend build from this directory instead of the embedded one")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	server, err := web.NewServer(web.Config{SnippetDir: *snippetDir, FrontendDir: *frontendDir})
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+
+	server.Announce(stdout, *port)
+	if err := http.ListenAndServe(":"+*port, server); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	return exitOK
+}
+
+func printJSON(v interface{}) int {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fail(exitError, "%v", err)
+	}
+	return exitOK
+}
+
+// treeFields are the child keys produced by ConvertASTToJSON, in print order
+var treeFields = []string{
+	"Name", "Value", "ReturnValue", "Expression", "Left", "Right",
+	"Condition", "Consequence", "Alternative", "Parameters", "Body",
+	"Function", "Arguments", "statements",
+}
+
+// printTree renders ConvertASTToJSON output as an indented outline
+func printTree(w io.Writer, node map[string]interface{}, label string, depth int) {
+	line := strings.Repeat("  ", depth) + label + fmt.Sprint(node["type"])
+	if op, ok := node["Operator"]; ok {
+		line += " " + fmt.Sprint(op)
+	}
+	if value, ok := node["Value"]; ok {
+		if _, isNode := value.(map[string]interface{}); !isNode {
+			line += " " + fmt.Sprint(value)
+		}
+	}
+
+	hasChildren := false
+	for _, field := range treeFields {
+		switch node[field].(type) {
+		case map[string]interface{}, []map[string]interface{}:
+			hasChildren = true
+		}
+	}
+	if !hasChildren && node["Token"] == nil {
+		line += "  " + fmt.Sprint(node["string"])
+	}
+	fmt.Fprintln(w, line)
+
+	for _, field := range treeFields {
+		fieldLabel := field + ": "
+		if field == "statements" {
+			fieldLabel = ""
+		}
+		switch child := node[field].(type) {
+		case map[string]interface{}:
+			printTree(w, child, fieldLabel, depth+1)
+		case []map[string]interface{}:
+			for _, c := range child {
+				printTree(w, c, fieldLabel, depth+1)
+			}
+		}
+	}
+}
+
+func indent(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			out.WriteString(prefix + line)
+		}
+	}
+	return out.String()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/session"
+)
+
+// engine executes parsed programs, keeping state between calls so the
+// REPL can build on earlier input
+type engine interface {
+	Run(program *ast.Program) (object.Object, error)
+}
+
+// newEngine returns a session on the engine selected by the -engine flag
+// with the prelude modules of the -prelude flag loaded; import() resolves
+// through loader and every run stops once it goes over b
+func newEngine(name string, loader *modules.Loader, preludeNames []string, b budget) (engine, error) {
+	s, err := session.New(name, loader)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LoadPrelude(preludeNames); err != nil {
+		return nil, err
+	}
+	return terminalEngine{s, b}, nil
+}
+
+// preludeFlag splits the comma-separated -prelude flag
+func preludeFlag(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// budget bounds each program, or each REPL input, an engine runs
+type budget struct {
+	steps   int
+	timeout time.Duration
+}
+
+// budgetFlags registers the -max-steps and -timeout flags on fs
+func budgetFlags(fs *flag.FlagSet) *budget {
+	b := &budget{}
+	fs.IntVar(&b.steps, "max-steps", 0, "stop a run after this many steps (0 for no limit)")
+	fs.DurationVar(&b.timeout, "timeout", 30*time.Second, "stop a run that takes longer than this (0 for no limit)")
+	return b
+}
+
+// limits starts the budget of one run
+func (b budget) limits() object.Limits {
+	return object.Budget(context.Background(), b.steps, b.timeout)
+}
+
+// terminalEngine runs programs within a budget, with puts writing to
+// stdout
+type terminalEngine struct {
+	session *session.Session
+	budget  budget
+}
+
+func (e terminalEngine) Run(program *ast.Program) (object.Object, error) {
+	return e.session.Run(program, stdout, e.budget.limits())
+}
+
+// describe formats err with its position and the Monkey stack trace, when
+// the engine recorded them
+func describe(err error) string {
+	var located *object.Error
+	if !errors.As(err, &located) || located.Line == 0 {
+		return err.Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (line %d, column %d)", located.Message, located.Line, located.Column)
+	for _, frame := range located.Stack {
+		fmt.Fprintf(&b, "\n    at %s (%d:%d)", frame.Function, frame.Line, frame.Column)
+	}
+	return b.String()
+}
+
+// fileLoader resolves imports relative to the directory of the program
+// file, or the working directory when reading stdin or running the REPL
+func fileLoader(args []string) *modules.Loader {
+	if len(args) == 0 || args[0] == "-" {
+		return modules.NewLoader(modules.Dir("."), "")
+	}
+	return modules.NewLoader(modules.Dir(filepath.Dir(args[0])), filepath.Base(args[0]))
+}
+
+// parse turns source into a program, joining every parser error
+func parse(code string) (*ast.Program, error) {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	return program, nil
+}
@@ -0,0 +1,110 @@
+// Command monkey runs the playground pipeline from the terminal.
+//
+//	monkey tokenize [file]
+//	monkey parse [-format json|tree] [file]
+//	monkey compile [file]
+//	monkey run [-engine vm|eval] [file]
+//	monkey repl [-engine vm|eval]
+//	monkey serve [-port 8080]
+//
+// Source is read from the named file, or from stdin when the file is
+// omitted or "-".
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes returned by every subcommand
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+// The streams commands read and write, replaced by tests
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+func init() {
+	commands = []command{
+		{"tokenize", "print the tokens of a program", runTokenize},
+		{"parse", "print the AST of a program as JSON or a tree", runParse},
+		{"compile", "print the constants and bytecode disassembly", runCompile},
+		{"run", "execute a program on the VM or the evaluator", runRun},
+		{"repl", "start an interactive session", runRepl},
+		{"serve", "start the playground HTTP API", runServe},
+	}
+}
+
+func main() {
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(stderr, "monkey: unknown command %q\n\n", name)
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: monkey <command> [flags] [file]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Source is read from stdin when no file (or \"-\") is given.")
+}
+
+// readSource loads the program named by the remaining arguments
+func readSource(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("expected at most one file, got %d", len(args))
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		data, err := io.ReadAll(stdin)
+		return string(data), err
+	}
+
+	data, err := os.ReadFile(args[0])
+	return string(data), err
+}
+
+// fail prints an error to stderr and returns the matching exit code
+func fail(code int, format string, a ...interface{}) int {
+	fmt.Fprintf(stderr, "monkey: "+format+"\n", a...)
+	return code
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"monkey-playground-backend/api"
+)
+
+// runCLI dispatches args with input as stdin and returns the exit code and
+// what was written to stdout and stderr
+func runCLI(t *testing.T, input string, args ...string) (int, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	in, prevOut, prevErr := stdin, stdout, stderr
+	stdin, stdout, stderr = strings.NewReader(input), &out, &errOut
+	t.Cleanup(func() { stdin, stdout, stderr = in, prevOut, prevErr })
+	code := dispatch(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		args   []string
+		input  string
+		code   int
+		stdout string // expected stdout, unless empty
+		stderr string // expected to appear in stderr, unless empty
+	}{
+		{[]string{"run"}, "puts(1 + 2); 5 * 2", exitOK, "3\n10\n", ""},
+		{[]string{"run", "-engine", "eval"}, "let f = fn(x) { x * 2 }; f(21)", exitOK, "42\n", ""},
+		{[]string{"run", "-q"}, `puts("hi"); 5`, exitOK, "hi\n", ""},
+		{[]string{"run", "-prelude", "math"}, "abs(-4)", exitOK, "4\n", ""},
+		{[]string{"run", "-"}, "1", exitOK, "1\n", ""},
+		{[]string{"run"}, "let = 5;", exitError, "", "parse error:"},
+		{[]string{"run"}, "1 + true", exitError, "", "runtime error: type mismatch: INTEGER + BOOLEAN (line 1, column 3)"},
+		{[]string{"run", "-engine", "eval"}, "let f = fn() { 1 + true };\n1 + f()", exitError, "", "type mismatch: INTEGER + BOOLEAN (line 1, column 18)\n    at f (2:5)"},
+		{[]string{"run", "-max-steps", "10000"}, "let f = fn() { f() }; f()", exitError, "", "runtime error: budget exceeded: more than 10000 steps"},
+		{[]string{"run", "-engine", "eval", "-timeout", "50ms"}, "let f = fn() { f() }; f()", exitError, "", "runtime error: budget exceeded: ran for more than 50ms"},
+		{[]string{"compile", "-max-steps", "10000"}, "let m = macro() { let f = fn() { f() }; f() }; m()", exitError, "", "macro error: budget exceeded"},
+		{[]string{"run", "-engine", "jit"}, "1", exitUsage, "", "jit"},
+		{[]string{"run", "-prelude", "nope"}, "1", exitUsage, "", `unknown prelude module "nope"`},
+		{[]string{"run", "-nope"}, "1", exitUsage, "", "flag provided but not defined: -nope"},
+		{[]string{"run", "a.monkey", "b.monkey"}, "", exitUsage, "", "expected at most one file, got 2"},
+		{[]string{"run", "missing.monkey"}, "", exitUsage, "", "missing.monkey"},
+		{[]string{"tokenize"}, "let x", exitOK, "    0  LET        let\n    3  IDENT      x\n", ""},
+		{[]string{"tokenize"}, "@", exitError, "", ""},
+		{[]string{"parse", "-format", "tree"}, "-x", exitOK, "Program\n  ExpressionStatement\n    Expression: PrefixExpression -\n      Right: Identifier x\n", ""},
+		{[]string{"parse", "-format", "yaml"}, "1", exitUsage, "", `unknown format "yaml" (want json or tree)`},
+		{[]string{"parse"}, "let = 5;", exitError, "", "parse error:"},
+		{[]string{"compile"}, "fn(x) { x }", exitOK, "", ""},
+		{[]string{"compile"}, "y", exitError, "", "compile error: undefined variable y"},
+		{[]string{"repl", "-history", ""}, "let f = fn(x) {\nx + 1\n};\nf(1)\n:quit\n", exitOK, "", ""},
+		{[]string{"repl", "-history", ""}, "1 + true\n", exitError, "", ""},
+		{[]string{"help"}, "", exitOK, "", ""},
+		{nil, "", exitUsage, "", "Usage: monkey"},
+		{[]string{"fly"}, "", exitUsage, "", `unknown command "fly"`},
+	}
+
+	for _, tt := range tests {
+		code, out, errOut := runCLI(t, tt.input, tt.args...)
+		if code != tt.code {
+			t.Errorf("%v: exit code %d, want %d (stderr %q)", tt.args, code, tt.code, errOut)
+		}
+		if tt.stdout != "" && out != tt.stdout {
+			t.Errorf("%v: stdout %q, want %q", tt.args, out, tt.stdout)
+		}
+		if !strings.Contains(errOut, tt.stderr) {
+			t.Errorf("%v: stderr %q, want it to contain %q", tt.args, errOut, tt.stderr)
+		}
+	}
+}
+
+func TestParseJSONMatchesAPI(t *testing.T) {
+	inputs := []string{
+		"let add = fn(a, b) { a + b }; add(1, 2 * 3);",
+		`if (x < 1) { [1, "two"] } else { {"a": true}[0] }`,
+		"return !-5;",
+	}
+
+	for _, input := range inputs {
+		code, out, errOut := runCLI(t, input, "parse")
+		if code != exitOK {
+			t.Fatalf("%q: exit code %d: %s", input, code, errOut)
+		}
+
+		program, err := parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal(api.ConvertASTToJSON(program))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got, expected any
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("%q: output is not JSON: %v", input, err)
+		}
+		json.Unmarshal(want, &expected)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: parse output differs from ConvertASTToJSON:\n%s\nwant\n%s", input, out, want)
+		}
+	}
+}
+
+func TestReplInteractive(t *testing.T) {
+	eng, err := newEngine("vm", fileLoader(nil), nil, budget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := &repl{engine: eng, engineName: "vm", out: &out, interactive: true}
+	if code := r.start(strings.NewReader("1 + true\nlet = 1;\n")); code != exitOK {
+		t.Errorf("exit code %d, want %d: failed entries at a terminal are not errors", code, exitOK)
+	}
+	if !strings.Contains(out.String(), "ERROR: type mismatch") {
+		t.Errorf("output %q does not report the failed entry", out.String())
+	}
+}
+
+func TestReplOutput(t *testing.T) {
+	input := "let double = fn(x) {\n  x * 2\n};\ndouble(4)\n!2\n:reset\ndouble\n"
+	code, out, _ := runCLI(t, input, "repl", "-history", "", "-engine", "eval")
+	if code != exitError {
+		t.Errorf("exit code %d, want %d after the failed entry", code, exitError)
+	}
+	for _, want := range []string{"8\n", "double(4)\n8\n", "environment reset\n", "ERROR: undefined variable double"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+}
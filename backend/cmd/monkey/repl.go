@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	prompt         = ">> "
+	continuePrompt = ".. "
+	historyLimit   = 500
+)
+
+func runRepl(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	engineName := fs.String("engine", "vm", "execution engine: vm or eval")
+	historyPath := fs.String("history", defaultHistoryPath(), "file used to persist input history (empty to disable)")
+	preludeNames := fs.String("prelude", "", "comma-separated prelude modules to load, e.g. core,math")
+	limits := budgetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	eng, err := newEngine(*engineName, fileLoader(nil), preludeFlag(*preludeNames), *limits)
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	r := &repl{
+		engine:      eng,
+		engineName:  *engineName,
+		prelude:     preludeFlag(*preludeNames),
+		budget:      *limits,
+		historyPath: *historyPath,
+		history:     loadHistory(*historyPath),
+		out:         stdout,
+		interactive: isTerminal(stdin),
+	}
+	return r.start(stdin)
+}
+
+// isTerminal reports whether in is a terminal rather than a pipe or a file
+func isTerminal(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// repl reads entries from the terminal, running each once its brackets
+// balance so functions and hashes can span several lines. Read from a pipe,
+// it exits with an error if any entry failed, so scripts can check it; at
+// a terminal a failed entry is just part of the session.
+type repl struct {
+	engine      engine
+	engineName  string
+	prelude     []string
+	budget      budget
+	historyPath string
+	history     []string
+	out         io.Writer
+	interactive bool
+	failed      bool
+}
+
+func (r *repl) start(in io.Reader) int {
+	fmt.Fprintf(r.out, "Monkey REPL (%s engine). Type :help for commands.\n", r.engineName)
+
+	scanner := bufio.NewScanner(in)
+	var pending []string
+
+	for {
+		if len(pending) == 0 {
+			fmt.Fprint(r.out, prompt)
+		} else {
+			fmt.Fprint(r.out, continuePrompt)
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			break
+		}
+		line := scanner.Text()
+
+		if len(pending) == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, ":") || isHistoryRef(trimmed) {
+				if done := r.command(trimmed); done {
+					break
+				}
+				continue
+			}
+		}
+
+		pending = append(pending, line)
+		entry := strings.Join(pending, "\n")
+		if openDelimiters(entry) > 0 {
+			continue
+		}
+
+		pending = nil
+		r.addHistory(entry)
+		r.eval(entry)
+	}
+
+	if r.failed {
+		return exitError
+	}
+	return exitOK
+}
+
+// command handles the REPL meta commands; it reports whether to exit
+func (r *repl) command(input string) bool {
+	switch {
+	case input == ":quit" || input == ":exit" || input == ":q":
+		return true
+	case input == ":help":
+		fmt.Fprintln(r.out, "  :history   list previous entries")
+		fmt.Fprintln(r.out, "  !<n>       re-run history entry n")
+		fmt.Fprintln(r.out, "  :reset     discard all bindings")
+		fmt.Fprintln(r.out, "  :quit      leave the REPL")
+	case input == ":history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+	case input == ":reset":
+		eng, err := newEngine(r.engineName, fileLoader(nil), r.prelude, r.budget)
+		if err != nil {
+			r.fail()
+			fmt.Fprintf(r.out, "ERROR: %v\n", err)
+			return false
+		}
+		r.engine = eng
+		fmt.Fprintln(r.out, "environment reset")
+	case isHistoryRef(input):
+		n, _ := strconv.Atoi(input[1:])
+		if n < 1 || n > len(r.history) {
+			fmt.Fprintf(r.out, "no history entry %s\n", input[1:])
+			return false
+		}
+		entry := r.history[n-1]
+		fmt.Fprintln(r.out, entry)
+		r.addHistory(entry)
+		r.eval(entry)
+	default:
+		fmt.Fprintf(r.out, "unknown command %s (try :help)\n", input)
+	}
+	return false
+}
+
+// isHistoryRef reports whether input is a "!<n>" history recall rather
+// than a Monkey expression starting with the bang operator
+func isHistoryRef(input string) bool {
+	if len(input) < 2 || input[0] != '!' {
+		return false
+	}
+	_, err := strconv.Atoi(input[1:])
+	return err == nil
+}
+
+func (r *repl) eval(entry string) {
+	program, err := parse(entry)
+	if err != nil {
+		r.fail()
+		fmt.Fprintf(r.out, "parse error:\n%s\n", indent(err.Error(), "  "))
+		return
+	}
+
+	result, err := r.engine.Run(program)
+	if err != nil {
+		r.fail()
+		fmt.Fprintf(r.out, "ERROR: %s\n", describe(err))
+		return
+	}
+
+	if result != nil {
+		fmt.Fprintln(r.out, result.Inspect())
+	}
+}
+
+// fail records a failed entry for the exit code of a piped session
+func (r *repl) fail() {
+	if !r.interactive {
+		r.failed = true
+	}
+}
+
+func (r *repl) addHistory(entry string) {
+	r.history = append(r.history, entry)
+	if len(r.history) > historyLimit {
+		r.history = r.history[len(r.history)-historyLimit:]
+	}
+	saveHistory(r.historyPath, r.history)
+}
+
+// openDelimiters counts brackets left open in code, ignoring strings and
+// line comments
+func openDelimiters(code string) int {
+	depth := 0
+	inString := false
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch {
+		case inString:
+			if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '/' && i+1 < len(code) && code[i+1] == '/':
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// loadHistory reads entries stored one quoted string per line
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, err := strconv.Unquote(line); err == nil {
+			history = append(history, entry)
+		}
+	}
+	return history
+}
+
+func saveHistory(path string, history []string) {
+	if path == "" {
+		return
+	}
+
+	var out strings.Builder
+	for _, entry := range history {
+		out.WriteString(strconv.Quote(entry))
+		out.WriteString("\n")
+	}
+	os.WriteFile(path, []byte(out.String()), 0o600)
+}
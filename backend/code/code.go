@@ -0,0 +1,189 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+type Instructions []byte
+
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		// Skip the unknown byte so the rest of the listing still prints
+		if err != nil { fmt.Fprintf(&out, "%04d ERROR: %s\n", i, err); i++; continue }
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+	if len(operands) != operandCount { return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount) }
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpAdd
+	OpPop
+	OpSub
+	OpMul
+	OpDiv
+	OpTrue
+	OpFalse
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpBang
+	OpMinus
+	OpJump
+	OpJumpNotTruthy
+	OpNull
+	OpGetGlobal
+	OpSetGlobal
+    OpArray
+    OpHash
+    OpIndex
+    OpCall
+    OpReturnValue
+    OpReturn
+    OpGetLocal
+    OpSetLocal
+    OpGetBuiltin
+    OpClosure
+    OpGetFree
+    OpCurrentClosure
+    OpLessThan
+    OpTailCall
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:    {"OpConstant", []int{2}},
+	OpAdd:         {"OpAdd", []int{}},
+	OpPop:         {"OpPop", []int{}},
+	OpSub:         {"OpSub", []int{}},
+	OpMul:         {"OpMul", []int{}},
+	OpDiv:         {"OpDiv", []int{}},
+	OpTrue:        {"OpTrue", []int{}},
+	OpFalse:       {"OpFalse", []int{}},
+	OpEqual:       {"OpEqual", []int{}},
+	OpNotEqual:    {"OpNotEqual", []int{}},
+	OpGreaterThan: {"OpGreaterThan", []int{}},
+	OpBang:        {"OpBang", []int{}},
+	OpMinus:       {"OpMinus", []int{}},
+	OpJump:        {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpNull:        {"OpNull", []int{}},
+	OpGetGlobal:   {"OpGetGlobal", []int{2}},
+	OpSetGlobal:   {"OpSetGlobal", []int{2}},
+    OpArray:      {"OpArray", []int{2}},
+    OpHash:       {"OpHash", []int{2}},
+    OpIndex:      {"OpIndex", []int{}},
+    OpCall:       {"OpCall", []int{1}},
+    OpReturnValue: {"OpReturnValue", []int{}},
+    OpReturn:     {"OpReturn", []int{}},
+    OpGetLocal:   {"OpGetLocal", []int{1}},
+    OpSetLocal:   {"OpSetLocal", []int{1}},
+    OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+    OpClosure:    {"OpClosure", []int{2, 1}},
+    OpGetFree:    {"OpGetFree", []int{1}},
+    OpCurrentClosure: {"OpCurrentClosure", []int{}},
+    OpLessThan:   {"OpLessThan", []int{}},
+    OpTailCall:   {"OpTailCall", []int{1}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok { return nil, fmt.Errorf("opcode %d undefined", op) }
+	return def, nil
+}
+
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok { return []byte{} }
+	instructionLen := 1
+	for _, w := range def.OperandWidths { instructionLen += w }
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+	return instruction
+}
+
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint8(ins Instructions) uint8 { return uint8(ins[0]) }
+
+func ReadUint16(ins Instructions) uint16 { return binary.BigEndian.Uint16(ins) }
+
+
+
+// SourcePos maps the instruction at Offset, and any after it up to the next
+// entry, to a line and column in the source. Callee names the function an
+// OpCall invokes when the call is made through an identifier.
+type SourcePos struct {
+	Offset int
+	Line   int
+	Column int
+	Callee string
+}
+
+// SourceMap lists SourcePos entries in increasing Offset order
+type SourceMap []SourcePos
+
+// Lookup returns the entry covering the instruction at offset
+func (m SourceMap) Lookup(offset int) SourcePos {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 { return SourcePos{} }
+	return m[i-1]
+}
+
+// Truncate drops entries for instructions at or after offset
+func (m SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset >= offset })
+	return m[:i]
+}
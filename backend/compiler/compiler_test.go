@@ -0,0 +1,162 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/code"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{input: "1 + 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpAdd), code.Make(code.OpPop)}},
+		{input: "1; 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpPop), code.Make(code.OpConstant, 1), code.Make(code.OpPop)}},
+		{input: "1 - 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpSub), code.Make(code.OpPop)}},
+		{input: "1 * 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpMul), code.Make(code.OpPop)}},
+		{input: "2 / 1", expectedConstants: []interface{}{2, 1}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpDiv), code.Make(code.OpPop)}},
+		{input: "-1", expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpMinus), code.Make(code.OpPop)}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{input: "true", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpPop)}},
+		{input: "false", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpFalse), code.Make(code.OpPop)}},
+		{input: "1 > 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpGreaterThan), code.Make(code.OpPop)}},
+		{input: "1 < 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpLessThan), code.Make(code.OpPop)}},
+		{input: "1 == 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpEqual), code.Make(code.OpPop)}},
+		{input: "1 != 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpNotEqual), code.Make(code.OpPop)}},
+		{input: "true == false", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpFalse), code.Make(code.OpEqual), code.Make(code.OpPop)}},
+		{input: "true != false", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpFalse), code.Make(code.OpNotEqual), code.Make(code.OpPop)}},
+		{input: "!true", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpBang), code.Make(code.OpPop)}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestClosuresCompiler(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn(a) { fn(b) { a + b } }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	for _, tt := range tests {
+		program := parse(tt.input)
+		compiler := New()
+		if err := compiler.Compile(program); err != nil { t.Fatalf("compiler error: %s", err) }
+		bytecode := compiler.Bytecode()
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil { t.Fatalf("testInstructions failed: %s", err) }
+		if err := testConstants(t, tt.expectedConstants, bytecode.Constants); err != nil { t.Fatalf("testConstants failed: %s", err) }
+	}
+}
+
+func parse(input string) *ast.Program { l := lexer.New(input); p := parser.New(l); return p.ParseProgram() }
+
+func testInstructions(expected []code.Instructions, actual code.Instructions) error {
+	concatted := concatInstructions(expected)
+	if len(actual) != len(concatted) { return fmt.Errorf("wrong instructions length.\nwant=%q\ngot =%q", concatted, actual) }
+	for i, ins := range concatted { if actual[i] != ins { return fmt.Errorf("wrong instruction at %d.\nwant=%q\ngot =%q", i, concatted, actual) } }
+	return nil
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions { out := code.Instructions{}; for _, ins := range s { out = append(out, ins...) }; return out }
+
+func testConstants(t *testing.T, expected []interface{}, actual []object.Object) error {
+	if len(expected) != len(actual) { return fmt.Errorf("wrong number of constants. got=%d, want=%d", len(actual), len(expected)) }
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			if err := testIntegerObject(int64(constant), actual[i]); err != nil { return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err) }
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok { return fmt.Errorf("constant %d - not a function: %T", i, actual[i]) }
+			if err := testInstructions(constant, fn.Instructions); err != nil { return fmt.Errorf("constant %d - testInstructions failed: %s", i, err) }
+		}
+	}
+	return nil
+}
+
+func testIntegerObject(expected int64, actual object.Object) error {
+	result, ok := actual.(*object.Integer)
+	if !ok { return fmt.Errorf("object is not Integer. got=%T (%+v)", actual, actual) }
+	if result.Value != expected { return fmt.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected) }
+	return nil
+}
+
+
+
+func TestTailCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn(f) { f(1) }`,
+			expectedConstants: []interface{}{1, []code.Instructions{code.Make(code.OpGetLocal, 0), code.Make(code.OpConstant, 0), code.Make(code.OpTailCall, 1), code.Make(code.OpReturnValue)}},
+			expectedInstructions: []code.Instructions{code.Make(code.OpClosure, 1, 0), code.Make(code.OpPop)},
+		},
+		{
+			input: `fn(f) { 1 + f(1) }`,
+			expectedConstants: []interface{}{1, 1, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpGetLocal, 0), code.Make(code.OpConstant, 1), code.Make(code.OpCall, 1), code.Make(code.OpAdd), code.Make(code.OpReturnValue)}},
+			expectedInstructions: []code.Instructions{code.Make(code.OpClosure, 2, 0), code.Make(code.OpPop)},
+		},
+		{
+			input: `fn(f) { if (true) { f(1) } else { 2 } }`,
+			expectedConstants: []interface{}{1, 2, []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpJumpNotTruthy, 14), code.Make(code.OpGetLocal, 0), code.Make(code.OpConstant, 0), code.Make(code.OpTailCall, 1), code.Make(code.OpJump, 17), code.Make(code.OpConstant, 1), code.Make(code.OpReturnValue)}},
+			expectedInstructions: []code.Instructions{code.Make(code.OpClosure, 2, 0), code.Make(code.OpPop)},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBuiltinsAndGlobals(t *testing.T) {
+	tests := []compilerTestCase{
+		{input: `len([])`, expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{code.Make(code.OpGetBuiltin, builtinIndex("len")), code.Make(code.OpArray, 0), code.Make(code.OpCall, 1), code.Make(code.OpPop)}},
+		{input: `let x = 1; x`, expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpSetGlobal, 0), code.Make(code.OpGetGlobal, 0), code.Make(code.OpPop)}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct{ input, expected string }{
+		{"x", "undefined variable x"},
+		{"let f = fn() { y }; f", "undefined variable y"},
+	}
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil || err.Error() != tt.expected { t.Errorf("%q: got error %v, want %q", tt.input, err, tt.expected) }
+	}
+}
+
+func builtinIndex(name string) int {
+	for i, b := range object.Builtins { if b.Name == name { return i } }
+	return -1
+}
@@ -0,0 +1,101 @@
+package compiler
+
+import (
+	"strings"
+
+	"monkey-playground-backend/code"
+	"monkey-playground-backend/object"
+)
+
+// Listing is the JSON view of compiled bytecode returned by /api/compile
+// and the WASM compile function
+type Listing struct {
+	// Bytecode is the main program's raw instructions, one number per byte
+	Bytecode []int `json:"bytecode"`
+	// Constants holds each constant's displayed value, by index
+	Constants    []string      `json:"constants"`
+	ConstantPool []Constant    `json:"constantPool"`
+	Instructions string        `json:"instructions"`
+	Disassembly  []Instruction `json:"disassembly"`
+}
+
+// Constant is one entry of the constant pool. Compiled functions also
+// carry their frame layout and their own instructions.
+type Constant struct {
+	Index         int           `json:"index"`
+	Type          string        `json:"type"`
+	Value         string        `json:"value"`
+	Name          string        `json:"name,omitempty"`
+	NumLocals     int           `json:"numLocals,omitempty"`
+	NumParameters int           `json:"numParameters,omitempty"`
+	Bytecode      []int         `json:"bytecode,omitempty"`
+	Instructions  string        `json:"instructions,omitempty"`
+	Disassembly   []Instruction `json:"disassembly,omitempty"`
+}
+
+// Instruction is one decoded instruction, located in the source when the
+// compiler recorded a position for it
+type Instruction struct {
+	Offset   int    `json:"offset"`
+	Opcode   string `json:"opcode"`
+	Operands []int  `json:"operands"`
+	Width    int    `json:"width"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// NewListing describes bytecode for display
+func NewListing(bytecode *Bytecode) Listing {
+	listing := Listing{
+		Bytecode:     bytesOf(bytecode.Instructions),
+		Constants:    make([]string, len(bytecode.Constants)),
+		ConstantPool: make([]Constant, len(bytecode.Constants)),
+		Instructions: bytecode.Instructions.String(),
+		Disassembly:  Disassemble(bytecode.Instructions, bytecode.SourceMap),
+	}
+
+	for i, c := range bytecode.Constants {
+		listing.Constants[i] = c.Inspect()
+		constant := Constant{Index: i, Type: string(c.Type()), Value: c.Inspect()}
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			constant.Name = fn.Name
+			constant.NumLocals = fn.NumLocals
+			constant.NumParameters = fn.NumParameters
+			constant.Bytecode = bytesOf(fn.Instructions)
+			constant.Instructions = fn.Instructions.String()
+			constant.Disassembly = Disassemble(fn.Instructions, fn.SourceMap)
+		}
+		listing.ConstantPool[i] = constant
+	}
+	return listing
+}
+
+// Disassemble decodes ins. An unknown opcode is listed as "ERROR: ..." with
+// a width of one byte and decoding continues after it.
+func Disassemble(ins code.Instructions, sourceMap code.SourceMap) []Instruction {
+	decoded := []Instruction{}
+	for i := 0; i < len(ins); {
+		instruction := Instruction{Offset: i, Operands: []int{}, Width: 1}
+		if def, err := code.Lookup(ins[i]); err != nil {
+			instruction.Opcode = "ERROR: " + strings.TrimSpace(err.Error())
+		} else {
+			operands, read := code.ReadOperands(def, ins[i+1:])
+			instruction.Opcode = def.Name
+			instruction.Operands = operands
+			instruction.Width += read
+		}
+		pos := sourceMap.Lookup(i)
+		instruction.Line, instruction.Column = pos.Line, pos.Column
+		decoded = append(decoded, instruction)
+		i += instruction.Width
+	}
+	return decoded
+}
+
+func bytesOf(ins code.Instructions) []int {
+	out := make([]int, len(ins))
+	for i, b := range ins {
+		out[i] = int(b)
+	}
+	return out
+}
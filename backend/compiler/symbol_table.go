@@ -0,0 +1,136 @@
+package compiler
+
+import "sort"
+
+// SymbolScope identifies where a symbol is defined
+// values: GLOBAL, LOCAL, BUILTIN, FREE, FUNCTION
+// kept brief per user preference
+
+type SymbolScope string
+
+const (
+	LocalScope    SymbolScope = "LOCAL"
+	GlobalScope   SymbolScope = "GLOBAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	FreeSymbols []Symbol
+
+	// numGlobals is shared between a program and the modules it imports so
+	// each module gets its own slots in the one globals store
+	numGlobals *int
+}
+
+// Clone copies a global table, so one table can seed several programs
+func (s *SymbolTable) Clone() *SymbolTable {
+	clone := NewSymbolTable()
+	for name, sym := range s.store { clone.store[name] = sym }
+	*clone.numGlobals = *s.numGlobals
+	return clone
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free, numGlobals: new(int)}
+}
+
+// NewModuleSymbolTable returns an empty global table for an imported module.
+// It sees the same builtins as program but none of its globals, and
+// allocates global indices after every slot program has used.
+func NewModuleSymbolTable(program *SymbolTable) *SymbolTable {
+	for program.Outer != nil { program = program.Outer }
+	s := NewSymbolTable()
+	s.numGlobals = program.numGlobals
+	for name, sym := range program.store {
+		if sym.Scope == BuiltinScope { s.store[name] = sym }
+	}
+	return s
+}
+
+// GlobalSymbols returns the global bindings defined in this table, sorted by name
+func (s *SymbolTable) GlobalSymbols() []Symbol {
+	symbols := []Symbol{}
+	for _, sym := range s.store {
+		if sym.Scope == GlobalScope { symbols = append(symbols, sym) }
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Name < symbols[j].Name })
+	return symbols
+}
+
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+		symbol.Index = *s.numGlobals
+		*s.numGlobals++
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+	return obj, ok
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+
@@ -0,0 +1,543 @@
+package evaluator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/vm"
+)
+
+// outcome is everything a user can observe from a run
+type outcome struct {
+	result string
+	value  string // object.Describe of the result, as JSON
+	output string
+	err    string
+	where  string // error position and stack trace
+}
+
+// where formats the position and stack trace recorded on err
+func where(err error) string {
+	var located *object.Error
+	if !errors.As(err, &located) {
+		return ""
+	}
+	s := fmt.Sprintf("%d:%d", located.Line, located.Column)
+	for _, frame := range located.Stack {
+		s += fmt.Sprintf(" < %s %d:%d", frame.Function, frame.Line, frame.Column)
+	}
+	return s
+}
+
+func runVM(t *testing.T, input string, limits object.Limits) outcome {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	// Macros are expanded before compiling, as session.Run does
+	var out bytes.Buffer
+	if err := evaluator.Expand(program, evaluator.NewEnvironment(nil, &out)); err != nil {
+		return outcome{output: out.String(), err: err.Error(), where: where(err)}
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return outcome{output: out.String(), err: err.Error(), where: where(err)}
+	}
+	machine := vm.New(comp.Bytecode())
+	machine.SetOutput(&out)
+	machine.SetLimits(limits)
+	if err := machine.Run(); err != nil {
+		return outcome{output: out.String(), err: err.Error(), where: where(err)}
+	}
+	result := machine.LastPoppedStackElem()
+	return outcome{result: result.Inspect(), value: describe(t, result), output: out.String()}
+}
+
+func runEval(t *testing.T, input string, limits object.Limits) outcome {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	var out bytes.Buffer
+	env := evaluator.NewEnvironment(nil, &out)
+	evaluator.SetLimits(env, limits)
+	result := evaluator.Eval(program, env)
+	if errObj, ok := result.(*object.Error); ok {
+		return outcome{output: out.String(), err: errObj.Message, where: where(errObj)}
+	}
+	return outcome{result: result.Inspect(), value: describe(t, result), output: out.String()}
+}
+
+func describe(t *testing.T, obj object.Object) string {
+	data, err := json.Marshal(object.Describe(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func assertConformance(t *testing.T, name, input string) outcome {
+	t.Helper()
+	return assertConformanceWith(t, name, input, object.Limits{})
+}
+
+func assertConformanceWith(t *testing.T, name, input string, limits object.Limits) outcome {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("%s: parse errors: %v", name, p.Errors())
+	}
+
+	vmOut, evalOut := runVM(t, input, limits), runEval(t, input, limits)
+	vmSeen, evalSeen := vmOut, evalOut
+	if strings.Contains(input, "spawn(") {
+		// Tasks run in parallel, so what they print varies from run to run
+		vmSeen.output, evalSeen.output = "", ""
+	}
+	if vmSeen != evalSeen {
+		t.Errorf("%s: engines disagree\nvm:   %+v\neval: %+v", name, vmOut, evalOut)
+	}
+	return vmOut
+}
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // result, or the error message prefixed with "error: "
+	}{
+		{`"mon" + "key"`, "monkey"},
+		{`"a" == "a"`, "true"},
+		{`"a" != "b"`, "true"},
+		{`[1] == [1]`, "true"},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, "true"},
+		{`[1, 2] != [1, 3]`, "true"},
+		{`[1] == [1, 1]`, "false"},
+		{`[1] == 1`, "false"},
+		{`{"a": [1], "b": 2} == {"b": 2, "a": [1]}`, "true"},
+		{`{"a": 1} == {"a": 2}`, "false"},
+		{`{"a": 1} != {"b": 1}`, "true"},
+		{`"ab" == "a" + "b"`, "true"},
+		{`let f = fn() {}; [f == f, fn() {} == fn() {}, [f] == [f]]`, "[true, false, true]"},
+		{`let a = [1]; a == a`, "true"},
+		{`true == true`, "true"},
+		{`1 == true`, "false"},
+		{`1 < 2 == true`, "true"},
+		{`if (false) { 1 }`, "null"},
+		{`if (true) { let x = 1; }`, "null"},
+		{`if (true) { 10 } else { 20 }; 30`, "30"},
+		{`return 5; 10`, "5"},
+		{`let x = 1;`, "null"},
+		{``, "null"},
+		{`let f = fn(x) { if (x) { return 1; }; 2 }; [f(true), f(false)]`, "[1, 2]"},
+		{`let f = fn() { let y = 1; }; f()`, "null"},
+		{`if (false) { let y = 1; }; y`, "null"},
+		{`fn(a, b) { a }`, "fn(a, b) {\na\n}"},
+		{`{"b": 2, "a": 1, 3: true}`, "{b: 2, a: 1, 3: true}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`{2: len(1), 1: len("a", "b")}`, "error: argument to `len` not supported, got INTEGER"},
+		{`puts("x")`, "null"},
+		{`first([])`, "null"},
+		{`10 / 0`, "error: division by zero"},
+		{`9223372036854775807 + 1`, "9223372036854775808"},
+		{`-9223372036854775807 - 2`, "-9223372036854775809"},
+		{`let f = fn(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(25)`, "15511210043330985984000000"},
+		{`let f = fn(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(25) / f(23)`, "600"},
+		{`(9223372036854775807 * 4) / (9223372036854775807 * 4 - 9223372036854775807 * 4)`, "error: division by zero"},
+		{`let big = 9223372036854775807 * 4; [big > 9223372036854775807, big < 0, -big, big - big == 0]`, "[true, false, -36893488147419103228, true]"},
+		{`let big = 9223372036854775807 + 1; [big == 1 + 9223372036854775807, big != big + 1, {big: "x"}[big - 1 + 1]]`, "[true, true, x]"},
+		{`[-(-9223372036854775807 - 1), (-9223372036854775807 - 1) / -1, (-9223372036854775807 - 1) * -1]`, "[9223372036854775808, 9223372036854775808, 9223372036854775808]"},
+		{`[to_int("123456789012345678901234567890") / 10, sort([9223372036854775807 * 2, 1, -9223372036854775807 * 2])]`, "[12345678901234567890123456789, [-18446744073709551614, 1, 18446744073709551614]]"},
+		{`let big = 9223372036854775807 * 2; [[1, 2][big], substr("abc", big), len(range(big, big + 3))]`, "[null, , 0]"},
+		{`1 + true`, "error: type mismatch: INTEGER + BOOLEAN"},
+		{`1 < "a"`, "error: type mismatch: INTEGER < STRING"},
+		{`true + false`, "error: unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" - "b"`, "error: unknown operator: STRING - STRING"},
+		{`"a" < "b"`, "error: unknown operator: STRING < STRING"},
+		{`-true`, "error: unknown operator: -BOOLEAN"},
+		{`fn(x) { x }(1, 2)`, "error: wrong number of arguments: want=1, got=2"},
+		{`fn(x, y) { x }(1)`, "error: wrong number of arguments: want=2, got=1"},
+		{`5(1)`, "error: not a function: INTEGER"},
+		{`fn() {} + 1`, "error: type mismatch: FUNCTION + INTEGER"},
+		{`puts(1); x`, "error: undefined variable x"},
+		{`let f = fn() { g() }; let g = fn() { 1 }; f()`, "error: undefined variable g"},
+		{`let x = 0; let f = fn() { x }; let x = 5; [f(), x]`, "[0, 5]"},
+		{`fn() { let x = 0; let f = fn() { x }; let x = 5; [f(), x] }()`, "[0, 5]"},
+		{`let x = 1; let x = x + 1; x`, "2"},
+		{`let f = fn() { 1 }; let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(3)`, "0"},
+		{`1[0]`, "error: index operator not supported: INTEGER"},
+		{`{fn() {}: 1}`, "error: unusable as hash key: FUNCTION"},
+		{`{1: 2}[[]]`, "null"},
+		{`let h = {[1, "a"]: 1, [1, 1]: 2, []: 3}; [h[[1, "a"]], h[[1, 1]], h[[]], h[[1]]]`, "[1, 2, 3, null]"},
+		{`{[1]: 1, [1]: 2}`, "{[1]: 2}"},
+		{`{[[1, 2], 3]: true}[[[1, 2], 3]]`, "true"},
+		{`{[fn() {}]: 1}`, "error: unusable as hash key: ARRAY"},
+		{`{{}: 1}`, "error: unusable as hash key: HASH"},
+		{`len(1)`, "error: argument to `len` not supported, got INTEGER"},
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`len(split("héllo", ""))`, "5"},
+		{`join([1, "b", true], "-")`, "1-b-true"},
+		{`join(split("a b c", " "), "")`, "abc"},
+		{`[substr("monkey", 3), substr("monkey", 1, 3), substr("monkey", -3, 2), substr("monkey", 10), substr("monkey", 2, 100)]`, "[key, onk, ke, , nkey]"},
+		{`substr("monkey", 1, -1)`, "error: length given to `substr` must not be negative, got -1"},
+		{`substr("monkey")`, "error: wrong number of arguments. got=1, want=2 or 3"},
+		{`[index_of("banana", "an"), index_of("banana", "x"), index_of([1, "a"], "a"), index_of([[1]], [1])]`, "[1, -1, 1, 0]"},
+		{`[contains("team", "ea"), contains("team", "I"), contains([1, 2], 2)]`, "[true, false, true]"},
+		{`contains(1, 1)`, "error: argument to `contains` not supported, got INTEGER"},
+		{`index_of("a", 1)`, "error: second argument to `index_of` must be STRING, got INTEGER"},
+		{`[upper("MixEd"), lower("MixEd"), trim("  pad  ")]`, "[MIXED, mixed, pad]"},
+		{`upper(1)`, "error: argument to `upper` must be STRING, got INTEGER"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("a", 1, "b")`, "error: second argument to `replace` must be STRING, got INTEGER"},
+		{`chars("héj")`, "[h, é, j]"},
+		{`[repeat("ab", 3), repeat("x", 0)]`, "[ababab, ]"},
+		{`repeat("x", -1)`, "error: count given to `repeat` must not be negative, got -1"},
+		{`repeat("xx", 10000000)`, "error: result of `repeat` is too long (limit 16777216 bytes)"},
+		{`[to_string(12), to_string("s"), to_string([1, "a"]), to_string(true)]`, "[12, s, [1, a], true]"},
+		{`to_string(5) == "5"`, "true"},
+		{`[to_int("42"), to_int(" -7 "), to_int(3)]`, "[42, -7, 3]"},
+		{`to_int("4x")`, "error: could not convert \"4x\" to INTEGER"},
+		{`to_int(true)`, "error: argument to `to_int` not supported, got BOOLEAN"},
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map(["a", 1], to_string)`, "[a, 1]"},
+		{`let double = fn(x) { x * 2 }; map([], double)`, "[]"},
+		{`filter(range(10), fn(x) { x - x / 2 * 2 == 0 })`, "[0, 2, 4, 6, 8]"},
+		{`reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })`, "10"},
+		{`reduce([], "empty", fn(acc, x) { acc })`, "empty"},
+		{`let n = 0; map([1, 2], fn(x) { map([x], fn(y) { y + n }) })`, "[[1], [2]]"},
+		{`[sort([3, 1, 2]), sort(["b", "c", "a"]), sort([3, 1, 2], fn(a, b) { a > b })]`, "[[1, 2, 3], [a, b, c], [3, 2, 1]]"},
+		{`sort([[2, "b"], [1, "a"], [2, "a"]], fn(a, b) { a[0] < b[0] })`, "[[1, a], [2, b], [2, a]]"},
+		{`sort([1, "a"])`, "error: `sort` cannot compare STRING with INTEGER without a comparator"},
+		{`sort([2, 1], fn(a, b) { a + true })`, "error: type mismatch: INTEGER + BOOLEAN"},
+		{`map([1], fn(a, b) { a })`, "error: wrong number of arguments: want=2, got=1"},
+		{`map([1], 1)`, "error: second argument to `map` must be FUNCTION, got INTEGER"},
+		{`filter(1, fn(x) { x })`, "error: first argument to `filter` must be ARRAY, got INTEGER"},
+		{`[reverse([1, 2, 3]), reverse([])]`, "[[3, 2, 1], []]"},
+		{`[range(3), range(2, 5), range(5, 0, -2), range(3, 1)]`, "[[0, 1, 2], [2, 3, 4], [5, 3, 1], []]"},
+		{`range(0, 10, 0)`, "error: step given to `range` must not be zero"},
+		{`range(100000000)`, "error: result of `range` is too long (limit 16777216 elements)"},
+		{`[slice([1, 2, 3, 4], 1), slice([1, 2, 3, 4], 1, 3), slice([1, 2, 3, 4], -2), slice([1, 2], 3, 1)]`, "[[2, 3, 4], [2, 3], [3, 4], []]"},
+		{`[concat([1], [2, 3], []), concat()]`, "[[1, 2, 3], []]"},
+		{`concat([1], 2)`, "error: argument 2 to `concat` must be ARRAY, got INTEGER"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`let h = {"b": 2, "a": 1}; [keys(h), values(h), entries(h)]`, "[[b, a], [2, 1], [[b, 2], [a, 1]]]"},
+		{`let h = {"a": 1}; [has(h, "a"), has(h, "b"), delete(h, "a"), h]`, "[true, false, {}, {a: 1}]"},
+		{`merge({"a": 1, "b": 2}, {"b": 3}, {})`, "{a: 1, b: 3}"},
+		{`merge({"b": 1}, {"a": 2, "b": 3})`, "{b: 3, a: 2}"},
+		{`delete({"c": 1, "a": 2, "b": 3}, "a")`, "{c: 1, b: 3}"},
+		{`[has({[1]: 2}, [1]), delete({[1]: 2, 3: 4}, [1])]`, "[true, {3: 4}]"},
+		{`has({}, [puts])`, "error: unusable as hash key: ARRAY"},
+		{`error("boom")`, "error: boom"},
+		{`error([1, "a"])`, "error: [1, a]"},
+		{`try(fn() { 5 }, fn(e) { 0 })`, "5"},
+		{`try(fn() { len(1) }, fn(e) { e["message"] })`, "argument to `len` not supported, got INTEGER"},
+		{`try(fn() { 1 / 0 }, fn(e) { [e["message"], e["payload"]] })`, "[division by zero, null]"},
+		{`try(fn() { error({"code": 7}) }, fn(e) { [e["message"], e["payload"]["code"]] })`, "[{code: 7}, 7]"},
+		{`let f = fn() { error("bad") }; try(fn() { f() }, fn(e) { [e["line"], e["column"], e["stack"]] })`, "[1, 16, [{function: f, line: 1, column: 32}]]"},
+		{`try(fn() { try(fn() { error("inner") }, fn(e) { error(e["message"] + "!") }) }, fn(e) { e["message"] })`, "inner!"},
+		{`try(fn() { error("x") }, fn(e) { error("again") })`, "error: again"},
+		{`let r = try(fn() { error("x") }, fn(e) { 1 }); r + 1`, "2"},
+		{`map([1, 0, 2], fn(x) { try(fn() { 10 / x }, fn(e) { -1 }) })`, "[10, -1, 5]"},
+		{`let f = fn(n) { f(n + 1) + 1 }; [try(fn() { f(0) }, fn(e) { e["message"] }), f]`, "[stack overflow, fn(n) {\n(f((n + 1)) + 1)\n}]"},
+		{`try(fn() { 1 }, 2)`, "error: second argument to `try` must be FUNCTION, got INTEGER"},
+		{`try(fn() { error("x") }, len)`, "error: argument to `len` not supported, got HASH"},
+		{`keys([])`, "error: argument to `keys` must be HASH, got ARRAY"},
+		{`let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) }; unless(1 > 2, "less", "greater")`, "less"},
+		{`let swap = macro(a, b) { quote(unquote(b) - unquote(a)) }; let f = fn(x) { swap(x, 10) }; f(3)`, "7"},
+		{`let twice = macro(x) { quote(unquote(x) + unquote(x)) }; twice(twice(2))`, "8"},
+		{`let big = macro() { quote(unquote(9223372036854775807 * 4) + 1) }; [big(), -big()]`, "[36893488147419103229, -36893488147419103229]"},
+		{`[92233720368547758070, 92233720368547758070 / 10]`, "[92233720368547758070, 9223372036854775807]"},
+		{`let m = macro() { puts("expanding"); quote(1) }; puts("running"); m()`, "1"},
+		{`let m = macro(x) { 1 }; m(2)`, "error: macro m must return a quote, got INTEGER"},
+		{`let m = macro(x) { x }; m()`, "error: wrong number of arguments: want=1, got=0"},
+		{`host("alert", 1)`, "error: host function \"alert\" is not available"},
+		{`host(1)`, "error: first argument to `host` must be STRING, got INTEGER"},
+		{`puts("before"); push(1, 2)`, "error: first argument to `push` must be ARRAY, got INTEGER"},
+		{`let f = fn(n) { 1 + f(n + 1) }; f(0)`, "error: stack overflow"},
+		{`let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)`, "0"},
+		{`let loop = fn(n) { if (n == 0) { return "done"; }; return loop(n - 1); }; loop(100000)`, "done"},
+		{`let sum = fn(n, acc) { if (n == 0) { acc } else { let next = acc + n; sum(n - 1, next) } }; sum(100000, 0)`, "5000050000"},
+		{`let f = fn(n) { return if (n > 0) { f(n - 1) } else { len("ok") }; }; f(5000)`, "2"},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } }; f(5000)`, "null"},
+		{`return fn(x) { x }(1); 2`, "1"},
+		{`let c = chan(); recv(c)`, "error: " + object.Deadlock},
+		{`let c = chan(); spawn(fn() { send(c, 1) }); 2`, "error: " + object.Deadlock},
+		{`let c = chan(); try(fn() { recv(c) }, fn(e) { 0 })`, "error: " + object.Deadlock},
+		{`let c = chan(); spawn(fn() { recv(c) }); spawn(fn() { 1 / 0 }); send(c, 1); 2`, "error: division by zero"},
+		{`spawn(fn() { puts("task") }); puts("main"); 1`, "1"},
+		{`spawn(fn(a, b) { puts(a + b) }, 1, 2); 3`, "3"},
+		{`spawn(fn() { 1 })`, "null"},
+		{`spawn(fn(x) { x })`, "error: wrong number of arguments: want=1, got=0"},
+		{`let c = chan(); close(c); close(c)`, "error: close of closed channel"},
+		{`let c = chan(1); close(c); send(c, 1)`, "error: send on closed channel"},
+		{`let c = chan(); spawn(fn() { send(c, 1) }); spawn(fn() { close(c) }); 1`, "error: send on closed channel"},
+		{`let c = chan(); spawn(fn() { close(c) }); recv(c)`, "null"},
+		{`chan(-1)`, "error: capacity given to `chan` must be between 0 and 65536, got -1"},
+		{`let c = chan(2); send(c, [1]); send(c, {"a": 2}); [recv(c), recv(c)]`, `[[1], {a: 2}]`},
+		{`chan() == chan()`, "false"},
+	}
+
+	for _, tt := range tests {
+		got := assertConformance(t, tt.input, tt.input)
+		gotStr := got.result
+		if got.err != "" {
+			gotStr = "error: " + got.err
+		}
+		if gotStr != tt.want {
+			t.Errorf("%q: got=%q, want=%q", tt.input, gotStr, tt.want)
+		}
+	}
+}
+
+// TestParallelTasks runs programs that only finish when tasks run in
+// parallel: a task that never blocks is stopped by another one failing
+func TestParallelTasks(t *testing.T) {
+	if runtime.GOARCH == "wasm" {
+		t.Skip("tasks take turns in WASM")
+	}
+	tests := []string{
+		`let spin = fn() { spin() }; spawn(spin); spawn(fn() { 1 / 0 }); 1`,
+		`spawn(fn() { 1 / 0 }); let spin = fn() { spin() }; spin()`,
+		`let c = chan(); let spin = fn() { spin() }; spawn(spin); spawn(fn() { send(c, 1 / 0) }); recv(c)`,
+	}
+	for _, input := range tests {
+		if got := assertConformance(t, input, input); got.err != "division by zero" {
+			t.Errorf("%q: got error %q, want division by zero", input, got.err)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		where string // line:column, then each call innermost first
+	}{
+		{`1 + true`, "1:3"},
+		{"let x = 1;\nlet y = x / 0;", "2:11"},
+		{"let f = fn() {\n  missing\n};", "2:3"},
+		{"let inner = fn(x) { x + \"a\" };\nlet outer = fn(y) { inner(y) };\nouter(1);", "1:23 < inner 3:1"},
+		{"let apply = fn(g) { g() };\napply(fn() { [][\"k\"] });", "2:16 < g 2:1"},
+		{"let f = fn(n) { 1 + f(n + 1) };\nf(0)", "1:21" + strings.Repeat(" < f 1:21", object.MaxStackFrames)},
+		{"let f = fn(n) { if (n == 0) { 1 + true } else { f(n - 1) } };\nf(3)", "1:33 < f 2:1"},
+		{`len(1, 2)`, "1:1"},
+		{"let f = fn() { fn() { 1 - \"x\" }() };\nf()", "1:25 < <anonymous> 2:1"},
+		{"let g = fn() { 1 + true };\nlet f = fn(n) { if (n == 0) { 1 + g() } else { f(n - 1) } };\nf(3)", "1:18 < g 2:35 < f 3:1"},
+		{"let check = fn(x) { x + true };\nmap([1], check)", "1:23 < check 2:1"},
+		{"let f = fn() {\n  map([1], fn(x) { x + true })\n};\nf()", "2:22 < <anonymous> 2:3 < f 4:1"},
+		{"map([1], fn(a, b) { a })", "1:1"},
+		{"let m = macro(x) { x + 1 };\nm(2)", "1:22"},
+		{"let m = macro() { quote(1) };\nlet n = macro() { 2 };\nm() + n()", "3:7"},
+		{"let f = fn() { error(\"x\") };\nf()", "1:16 < f 2:1"},
+		{"try(fn() { 1 / 0 }, fn(e) {\n  e + 1\n})", "2:5 < <anonymous> 1:1"},
+		{"let f = fn(x) { x + true };\nlet g = fn() {\n  spawn(f, 1)\n};\ng()", "1:19 < f 3:3"},
+		{"let c = chan();\nspawn(fn() { recv(c) });\nrecv(c)", "3:1"},
+	}
+
+	for _, tt := range tests {
+		got := assertConformance(t, tt.input, tt.input)
+		if got.err == "" {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if got.where != tt.where {
+			t.Errorf("%q: got=%q, want=%q", tt.input, got.where, tt.where)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`5`, `{"type":"integer","value":5}`},
+		{`"5"`, `{"type":"string","value":"5"}`},
+		{`if (false) { 1 }`, `{"type":"null"}`},
+		{`[1, [true]]`, `{"elements":[{"type":"integer","value":1},{"elements":[{"type":"boolean","value":true}],"length":1,"type":"array"}],"length":2,"type":"array"}`},
+		{`{"b": 1, 2: "a"}`, `{"length":2,"pairs":[{"key":{"type":"string","value":"b"},"value":{"type":"integer","value":1}},{"key":{"type":"integer","value":2},"value":{"type":"string","value":"a"}}],"type":"hash"}`},
+		{`let add = fn(a, b) { a + b }; add`, `{"arity":2,"name":"add","parameters":["a","b"],"type":"function"}`},
+		{`fn() { 1 }`, `{"arity":0,"parameters":[],"type":"function"}`},
+		{`len`, `{"name":"len","type":"builtin"}`},
+		{`chan(4)`, `{"capacity":4,"type":"channel"}`},
+		{`9007199254740991 + 1`, `{"type":"integer","value":"9007199254740992"}`},
+		{`9223372036854775807 * 2`, `{"type":"integer","value":"18446744073709551614"}`},
+	}
+
+	for _, tt := range tests {
+		if got := assertConformance(t, tt.input, tt.input); got.value != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.input, got.value, tt.want)
+		}
+	}
+
+	// Huge and deep values are cut short
+	long := object.Describe(&object.String{Value: strings.Repeat("x", object.DescribeMaxString+1)})
+	if long["truncated"] != true || long["length"] != object.DescribeMaxString+1 {
+		t.Errorf("long string: got %v, %v", long["truncated"], long["length"])
+	}
+	big := &object.Array{Elements: make([]object.Object, object.DescribeMaxElements+1)}
+	if got := object.Describe(big); got["truncated"] != true || len(got["elements"].([]any)) != object.DescribeMaxElements {
+		t.Errorf("big array: got truncated=%v with %d elements", got["truncated"], len(got["elements"].([]any)))
+	}
+	deep := object.Object(&object.Array{})
+	for i := 0; i <= object.DescribeMaxDepth; i++ {
+		deep = &object.Array{Elements: []object.Object{deep}}
+	}
+	node := object.Describe(deep)
+	for i := 0; i < object.DescribeMaxDepth; i++ {
+		node = node["elements"].([]any)[0].(map[string]any)
+	}
+	if node["truncated"] != true || len(node["elements"].([]any)) != 0 {
+		t.Errorf("deep array: got %v at depth %d", node, object.DescribeMaxDepth)
+	}
+}
+
+// TestBuiltinExamples runs the documented examples of every builtin
+func TestBuiltinExamples(t *testing.T) {
+	for _, def := range object.Builtins {
+		for _, example := range def.Examples {
+			input, want, ok := strings.Cut(example, " // ")
+			if !ok {
+				t.Errorf("%s: example %q has no result", def.Name, example)
+				continue
+			}
+			if got := assertConformance(t, example, input); got.result != want || got.err != "" {
+				t.Errorf("%s: %s gave %q (error %q)", def.Name, input, got.result, got.err)
+			}
+		}
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	saved := object.Builtins
+	t.Cleanup(func() { object.Builtins = saved })
+
+	err := object.RegisterBuiltin(object.BuiltinDefinition{
+		Name:   "test_clamp",
+		Params: []object.Param{{Name: "n", Types: []object.ObjectType{object.INTEGER_OBJ}}, {Name: "limit", Types: []object.ObjectType{object.INTEGER_OBJ}, Optional: true}},
+		Fn: func(host object.Host, args ...object.Object) object.Object {
+			n, limit := args[0].(*object.Integer).Value, int64(10)
+			if len(args) == 2 {
+				limit = args[1].(*object.Integer).Value
+			}
+			return &object.Integer{Value: min(n, limit)}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`[test_clamp(50), test_clamp(5), test_clamp(50, 20)]`, "[10, 5, 20]"},
+		{`map([1, 100], test_clamp)`, "[1, 10]"},
+		{`test_clamp()`, "error: wrong number of arguments. got=0, want=1 or 2"},
+		{`test_clamp(1, "2")`, "error: second argument to `test_clamp` must be INTEGER, got STRING"},
+	}
+	for _, tt := range tests {
+		got := assertConformance(t, tt.input, tt.input)
+		if got.err != "" {
+			got.result = "error: " + got.err
+		}
+		if got.result != tt.want {
+			t.Errorf("%q: got=%q, want=%q", tt.input, got.result, tt.want)
+		}
+	}
+
+	invalid := []object.BuiltinDefinition{
+		{Name: "test_clamp", Fn: object.GetBuiltinByName("len").Fn},
+		{Name: "let", Fn: object.GetBuiltinByName("len").Fn},
+		{Name: "1st", Fn: object.GetBuiltinByName("len").Fn},
+		{Name: "test_nofn"},
+		{Name: "test_order", Params: []object.Param{{Name: "a", Optional: true}, {Name: "b"}}, Fn: object.GetBuiltinByName("len").Fn},
+	}
+	for _, def := range invalid {
+		if err := object.RegisterBuiltin(def); err == nil {
+			t.Errorf("%s: registered an invalid builtin", def.Name)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	cancelAfter := func(checks int) func(int) error {
+		return func(steps int) error {
+			if steps >= checks*object.CheckInterval {
+				return errors.New(object.Cancelled)
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		input  string
+		limits object.Limits
+		want   string
+	}{
+		{`let f = fn() { f() }; f()`, object.Limits{Monitor: cancelAfter(10)}, object.Cancelled},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } }; f(100)`, object.Limits{Monitor: cancelAfter(10)}, ""},
+		{`let f = fn(n) { 1 + f(n + 1) }; f(0)`, object.Limits{MaxDepth: 10}, "budget exceeded: more than 10 nested calls"},
+		{`let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(9)`, object.Limits{MaxDepth: 10}, ""},
+		{`let f = fn() { f() }; try(f, fn(e) { 0 })`, object.Limits{Monitor: cancelAfter(10)}, object.Cancelled},
+		{`let f = fn(n) { 1 + f(n + 1) }; try(fn() { f(0) }, fn(e) { 0 })`, object.Limits{MaxDepth: 10}, "budget exceeded: more than 10 nested calls"},
+	}
+
+	for _, tt := range tests {
+		// Step counts differ between the engines, so only depth budgets
+		// are held to identical outcomes
+		if tt.limits.Monitor != nil {
+			for engine, got := range map[string]outcome{"vm": runVM(t, tt.input, tt.limits), "eval": runEval(t, tt.input, tt.limits)} {
+				if got.err != tt.want {
+					t.Errorf("%s: %q: got error %q, want %q", engine, tt.input, got.err, tt.want)
+				}
+			}
+			continue
+		}
+		if got := assertConformanceWith(t, tt.input, tt.input, tt.limits); got.err != tt.want {
+			t.Errorf("%q: got error %q, want %q", tt.input, got.err, tt.want)
+		}
+	}
+}
+
+func TestConformancePrograms(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.monkey"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no conformance programs: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := assertConformance(t, file, string(data)); got.err != "" {
+			t.Errorf("%s: %s", file, got.err)
+		}
+	}
+}
+
+// samplePattern pulls id and code out of the playground's sample list
+var samplePattern = regexp.MustCompile("(?s)id: \"([^\"]+)\".*?code: `([^`]*)`")
+
+func TestConformanceSamples(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "frontend", "src", "data", "samples.ts"))
+	if err != nil {
+		t.Skipf("playground samples not available: %v", err)
+	}
+	samples := samplePattern.FindAllStringSubmatch(string(data), -1)
+	if len(samples) == 0 {
+		t.Fatal("no samples found in samples.ts")
+	}
+	for _, sample := range samples {
+		id, code := sample[1], strings.ReplaceAll(sample[2], "\\`", "`")
+		if got := assertConformance(t, id, code); got.err != "" {
+			t.Errorf("sample %s: %s", id, got.err)
+		}
+	}
+}
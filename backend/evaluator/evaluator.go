@@ -3,8 +3,8 @@ package evaluator
 import (
 	"fmt"
 
-	"github.com/NavrajBal/monkey-lang/ast"
-	"github.com/NavrajBal/monkey-lang/object"
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/object"
 )
 
 var (
@@ -13,7 +13,18 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// Eval evaluates node. Errors are located at the innermost node that
+// produced them, with the Monkey call stack at that point.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if h, ok := env.Host().(*evalHost); ok {
+		if err := h.step(); err != nil { return err }
+	}
+	result := eval(node, env)
+	if err, ok := result.(*object.Error); ok && err.Line == 0 { locate(err, node, env) }
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -22,7 +33,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := evalTail(node.ReturnValue, env)
 		if isError(val) { return val }
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
@@ -30,7 +41,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) { return val }
 		env.Set(node.Name.Value, val)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return &object.Integer{Value: node.Value, Big: node.Big}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -52,13 +63,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Name: node.Name, Parameters: params, Env: env.Snapshot(), Body: body, Hidden: node.Hidden}
 	case *ast.CallExpression:
+		if isCallOf(node, "quote") { return evalQuote(node, env) }
 		function := Eval(node.Function, env)
 		if isError(function) { return function }
 		args := evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) { return args[0] }
-		return applyFunction(function, args)
+		return applyFunction(env.Host(), function, args, callSite(node))
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -73,22 +85,41 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIndexExpression(left, index)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.MacroLiteral:
+		return newError("macros must be bound with let at the top level of a program")
 	}
 	return nil
 }
 
+// evalProgram runs program and, at the top level, waits for the tasks it
+// spawned
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	result := evalStatements(program, env)
+	h, _ := env.Host().(*evalHost)
+	if h == nil || h.task == nil || len(h.frames) > 0 { return result }
+	failed, _ := result.(*object.Error)
+	err := h.task.Finish(failed)
+	h.task = nil
+	if err != nil { return err }
+	return result
+}
+
+func evalStatements(program *ast.Program, env *object.Environment) object.Object {
+	if err := Expand(program, env); err != nil { return err }
+	if err := resolve(program, env); err != nil { return err }
 	var result object.Object
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 		switch result := result.(type) {
 		case *object.ReturnValue:
+			// A top-level return is not inside a function to replace
+			if call, ok := result.Value.(*tailCall); ok { return applyFunction(env.Host(), call.fn, call.args, call.site) }
 			return result.Value
 		case *object.Error:
 			return result
 		}
 	}
-	return result
+	return orNull(result)
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
@@ -126,9 +157,11 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -136,79 +169,76 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}
-}
+func evalBangOperatorExpression(right object.Object) object.Object { return nativeBoolToBooleanObject(!isTruthy(right)) }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.NegateInteger(right.(*object.Integer))
 }
 
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	leftVal := left.(*object.Integer)
+	rightVal := right.(*object.Integer)
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return object.AddIntegers(leftVal, rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return object.SubIntegers(leftVal, rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return object.MulIntegers(leftVal, rightVal)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		quotient, err := object.DivIntegers(leftVal, rightVal)
+		if err != nil { return err }
+		return quotient
 	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
+		return nativeBoolToBooleanObject(object.CompareIntegers(leftVal, rightVal) < 0)
 	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
+		return nativeBoolToBooleanObject(object.CompareIntegers(leftVal, rightVal) > 0)
 	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
+		return nativeBoolToBooleanObject(object.CompareIntegers(leftVal, rightVal) == 0)
 	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
+		return nativeBoolToBooleanObject(object.CompareIntegers(leftVal, rightVal) != 0)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	if operator != "+" { return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type()) }
+	return &object.String{Value: left.(*object.String).Value + right.(*object.String).Value}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) { return condition }
-	if isTruthy(condition) { return Eval(ie.Consequence, env) }
-	if ie.Alternative != nil { return Eval(ie.Alternative, env) }
+	if isTruthy(condition) { return orNull(Eval(ie.Consequence, env)) }
+	if ie.Alternative != nil { return orNull(Eval(ie.Alternative, env)) }
 	return NULL
 }
 
+// orNull gives blocks that end in a let, or are empty, the value null
+func orNull(obj object.Object) object.Object { if obj == nil { return NULL }; return obj }
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	// The resolver has seen a let for this name, but it has not run
+	return NULL
 }
 
 func isTruthy(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
 		return false
 	default:
 		return true
@@ -228,21 +258,64 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// callSite names a call after the identifier it is made through and
+// locates it for stack traces
+func callSite(node *ast.CallExpression) object.StackFrame {
+	line, column := ast.Position(node)
+	site := object.StackFrame{Line: line, Column: column}
+	if ident, ok := node.Function.(*ast.Identifier); ok { site.Function = ident.Value }
+	return site
+}
+
+func applyFunction(host object.Host, fn object.Object, args []object.Object, site object.StackFrame) object.Object {
+	h, _ := host.(*evalHost)
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		if len(args) != len(fn.Parameters) { return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args)) }
+		if h != nil && len(h.frames) >= maxCallDepth { return newError("stack overflow") }
+		if h != nil {
+			if err := h.limits.DepthExceeded(len(h.frames)); err != nil { return newError("%s", err) }
+		}
+		// Library code's names for the functions it calls stay out of
+		// traces, as they would for a builtin calling them
+		if h != nil && len(h.frames) > 0 && h.frames[len(h.frames)-1].Hidden { site.Function = "" }
+		// Calls in tail position come back as a tailCall and run in this
+		// frame, keeping its call site, instead of on a new Go stack frame
+		for {
+			if h != nil { h.push(frameFor(fn, site)) }
+			evaluated := unwrapReturnValue(evalTail(fn.Body, extendFunctionEnv(fn, args, host)))
+			if h != nil { h.pop() }
+			call, ok := evaluated.(*tailCall)
+			if !ok { return orNull(evaluated) }
+			hidden := fn.Hidden
+			fn, args, site.Function = call.fn, call.args, call.site.Function
+			if hidden { site.Function = "" }
+		}
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if h != nil { h.site = site }
+		if result := fn.Fn(host, args...); result != nil { return result }
+		return NULL
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// frameFor names a call to fn made at site for stack traces
+func frameFor(fn *object.Function, site object.StackFrame) object.StackFrame {
+	if fn.Name != "" { site.Function = fn.Name }
+	if site.Function == "" { site.Function = "<anonymous>" }
+	site.Hidden = fn.Hidden
+	return site
+}
+
+// extendFunctionEnv binds fn's parameters in a scope enclosed by fn's own.
+// A function named by its let also sees that name as itself, as compiled
+// functions do. The scope runs on the caller's host, so that a function
+// called in a task runs in that task.
+func extendFunctionEnv(fn *object.Function, args []object.Object, host object.Host) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
+	if host != nil { env.SetHost(host) }
+	if fn.Name != "" { env.Set(fn.Name, fn) }
 	for paramIdx, param := range fn.Parameters { env.Set(param.Value, args[paramIdx]) }
 	return env
 }
@@ -278,12 +351,12 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.HashKeyOf(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Pairs[key]
 	if !ok {
 		return NULL
 	}
@@ -292,29 +365,28 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.HashKeyOf(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 
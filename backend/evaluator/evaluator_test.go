@@ -3,9 +3,9 @@ package evaluator
 import (
 	"testing"
 
-	"github.com/NavrajBal/monkey-lang/lexer"
-	"github.com/NavrajBal/monkey-lang/object"
-	"github.com/NavrajBal/monkey-lang/parser"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -0,0 +1,214 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+)
+
+// maxCallDepth matches vm.MaxFrames, less the VM's main frame, so runaway
+// recursion fails at the same depth in both engines
+const maxCallDepth = 1023
+
+// evalHost is the per-run state behind an environment from NewEnvironment.
+// Each task spawned by the program runs on a host of its own.
+type evalHost struct {
+	loader  *modules.Loader
+	out     io.Writer
+	hostFns object.HostFunctions
+
+	// frames are the active Monkey calls, outermost first; site is the
+	// call site of the builtin being run
+	frames []object.StackFrame
+	site   object.StackFrame
+
+	limits object.Limits
+	steps  int
+
+	// task is the task running on this host, once the program uses tasks
+	task *object.Task
+}
+
+// NewEnvironment returns a top-level environment for one run. puts writes
+// to out (os.Stdout when nil) and import() resolves through loader, which
+// may be nil when the program has no module files. Each module is
+// evaluated in its own environment and exports its top-level bindings.
+func NewEnvironment(loader *modules.Loader, out io.Writer) *object.Environment {
+	h := &evalHost{loader: loader, out: out}
+	env := object.NewEnvironment()
+	env.SetHost(h)
+	if loader != nil {
+		loader.SetRunner(h.runModule)
+	}
+	return env
+}
+
+// NewEnclosedEnvironment is NewEnvironment for a run that starts with the
+// bindings of outer, such as a prelude's. The run only reads outer, so
+// many runs, even in parallel, can share it.
+func NewEnclosedEnvironment(outer *object.Environment, loader *modules.Loader, out io.Writer) *object.Environment {
+	env := object.NewEnclosedEnvironment(outer)
+	h := &evalHost{loader: loader, out: out}
+	env.SetHost(h)
+	if loader != nil {
+		loader.SetRunner(h.runModule)
+	}
+	return env
+}
+
+// runModule evaluates an imported module on h, the host importing it
+func (h *evalHost) runModule(file string, program *ast.Program) (map[string]object.Object, error) {
+	moduleEnv := object.NewEnvironment()
+	moduleEnv.SetHost(h)
+	if len(h.frames) >= maxCallDepth {
+		return nil, fmt.Errorf("stack overflow")
+	}
+	if err := h.limits.DepthExceeded(len(h.frames)); err != nil {
+		return nil, err
+	}
+	h.push(object.StackFrame{Function: file, Line: h.site.Line, Column: h.site.Column})
+	defer h.pop()
+	if result := Eval(program, moduleEnv); isError(result) {
+		return nil, result.(*object.Error)
+	}
+	bindings := make(map[string]object.Object)
+	for _, name := range moduleEnv.Names() {
+		bindings[name], _ = moduleEnv.Get(name)
+	}
+	return bindings, nil
+}
+
+// SetOutput redirects puts for later runs in env, which must come from
+// NewEnvironment; nil means os.Stdout
+func SetOutput(env *object.Environment, out io.Writer) {
+	if h, ok := env.Host().(*evalHost); ok {
+		h.out = out
+	}
+}
+
+// SetHostFunctions grants later runs in env, which must come from
+// NewEnvironment, the embedder functions host() may call
+func SetHostFunctions(env *object.Environment, fns object.HostFunctions) {
+	if h, ok := env.Host().(*evalHost); ok {
+		h.hostFns = fns
+	}
+}
+
+// SetLimits bounds runs in env, which must come from NewEnvironment
+func SetLimits(env *object.Environment, limits object.Limits) {
+	if h, ok := env.Host().(*evalHost); ok {
+		h.limits = limits
+	}
+}
+
+// step counts one evaluated node. Every object.CheckInterval steps it
+// stops the task if another one failed the run and consults the monitor.
+func (h *evalHost) step() *object.Error {
+	h.steps++
+	if h.steps%object.CheckInterval != 0 {
+		return nil
+	}
+	steps := h.steps
+	if h.task != nil {
+		var err *object.Error
+		if steps, err = h.task.Check(); err != nil {
+			return err
+		}
+	}
+	if h.limits.Monitor == nil {
+		return nil
+	}
+	if err := h.limits.Monitor(steps); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	return nil
+}
+
+// Import implements object.Host. Tasks import one at a time, each running
+// the module on its own host.
+func (h *evalHost) Import(path string) object.Object {
+	if h.loader == nil {
+		return newError("import %q: no module files available", path)
+	}
+	var exports object.Object
+	if err := h.Task().Exclusive(func() {
+		h.loader.SetRunner(h.runModule)
+		exports = h.loader.Import(path)
+	}); err != nil {
+		return err
+	}
+	return exports
+}
+
+// Output implements object.Host
+func (h *evalHost) Output() io.Writer {
+	if h.out == nil {
+		return os.Stdout
+	}
+	return h.out
+}
+
+// HostFunction implements object.Host
+func (h *evalHost) HostFunction(name string) object.HostFunction {
+	return h.hostFns[name]
+}
+
+// Apply implements object.Host. The call is located at the site of the
+// builtin making it and, having no identifier of its own, is named only
+// by the function's let binding.
+func (h *evalHost) Apply(fn object.Object, args ...object.Object) object.Object {
+	site := h.site
+	defer func() { h.site = site }()
+	return applyFunction(h, fn, args, object.StackFrame{Line: site.Line, Column: site.Column})
+}
+
+// Spawn implements object.Host. The task runs on a host of its own,
+// whose calls start with fn called at the site of spawn, and writes to the
+// same output.
+func (h *evalHost) Spawn(fn object.Object, args ...object.Object) *object.Error {
+	site := object.StackFrame{Line: h.site.Line, Column: h.site.Column}
+	h.out = object.SharedOutput(h.Output())
+	task := &evalHost{loader: h.loader, out: h.out, hostFns: h.hostFns, limits: h.limits}
+	return h.Task().Spawn(func(t *object.Task) object.Object {
+		task.task = t
+		return applyFunction(task, fn, args, site)
+	})
+}
+
+// Task implements object.Host
+func (h *evalHost) Task() *object.Task {
+	if h.task == nil {
+		h.task = object.NewScheduler(h.steps)
+	}
+	return h.task
+}
+
+func (h *evalHost) push(frame object.StackFrame) { h.frames = append(h.frames, frame) }
+
+func (h *evalHost) pop() { h.frames = h.frames[:len(h.frames)-1] }
+
+// stackTrace returns the active calls innermost first, as the VM reports them
+func (h *evalHost) stackTrace() []object.StackFrame {
+	var stack []object.StackFrame
+	for i := len(h.frames) - 1; i >= 0 && len(stack) < object.MaxStackFrames; i-- {
+		stack = append(stack, h.frames[i])
+	}
+	return stack
+}
+
+// locate records where err happened unless node has no position, in which
+// case an enclosing node will
+func locate(err *object.Error, node ast.Node, env *object.Environment) {
+	line, column := ast.Position(node)
+	if line == 0 {
+		return
+	}
+	err.Line, err.Column = line, column
+	if h, ok := env.Host().(*evalHost); ok {
+		err.Stack = h.stackTrace()
+	}
+}
@@ -0,0 +1,184 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/token"
+)
+
+// Expand runs the macro system over program before it is evaluated:
+// DefineMacros moves its macro definitions into env, then ExpandMacros
+// replaces the macro calls. The evaluator expands every program it runs;
+// the VM's callers expand programs with it before compiling.
+func Expand(program *ast.Program, env *object.Environment) *object.Error {
+	DefineMacros(program, env)
+	return ExpandMacros(program, env)
+}
+
+// DefineMacros binds each top-level let of a macro literal in env and
+// removes it from program
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	kept := []ast.Statement{}
+	for _, statement := range program.Statements {
+		if let, ok := statement.(*ast.LetStatement); ok {
+			if macro, ok := let.Value.(*ast.MacroLiteral); ok {
+				env.Set(let.Name.Value, &object.Macro{Parameters: macro.Parameters, Body: macro.Body, Env: env})
+				continue
+			}
+		}
+		kept = append(kept, statement)
+	}
+	program.Statements = kept
+}
+
+// ExpandMacros replaces every call of a macro bound in env with the code
+// the macro returns. The macro is evaluated with its parameters bound to
+// the quoted arguments and must return a quote.
+func ExpandMacros(program *ast.Program, env *object.Environment) *object.Error {
+	var failed *object.Error
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || failed != nil {
+			return node
+		}
+		macro, ok := macroFor(call, env)
+		if !ok {
+			return node
+		}
+
+		if len(call.Arguments) != len(macro.Parameters) {
+			failed = newError("wrong number of arguments: want=%d, got=%d", len(macro.Parameters), len(call.Arguments))
+		} else {
+			macroEnv := object.NewEnclosedEnvironment(macro.Env)
+			for i, param := range macro.Parameters {
+				macroEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+			}
+			switch result := unwrapReturnValue(Eval(macro.Body, macroEnv)).(type) {
+			case *object.Quote:
+				return result.Node
+			case *object.Error:
+				failed = result
+			case nil:
+				failed = newError("macro %s must return a quote, got NULL", call.Function.String())
+			default:
+				failed = newError("macro %s must return a quote, got %s", call.Function.String(), result.Type())
+			}
+		}
+		if failed.Line == 0 {
+			failed.Line, failed.Column = ast.Position(call)
+		}
+		return node
+	})
+	if failed != nil {
+		return failed
+	}
+	program.Statements = expanded.(*ast.Program).Statements
+	return nil
+}
+
+func macroFor(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+// isCallOf reports whether call is a call of the identifier name, as
+// quote and unquote calls are: they are evaluated specially rather than
+// being builtins
+func isCallOf(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// evalQuote returns the code of quote's argument, with each unquote(x)
+// in it replaced by the code for x's value
+func evalQuote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(call.Arguments))
+	}
+	var failed *object.Error
+	quoted := ast.Modify(call.Arguments[0], func(node ast.Node) ast.Node {
+		unquote, ok := node.(*ast.CallExpression)
+		if !ok || failed != nil || !isCallOf(unquote, "unquote") {
+			return node
+		}
+		if len(unquote.Arguments) != 1 {
+			failed = newError("wrong number of arguments. got=%d, want=1", len(unquote.Arguments))
+			failed.Line, failed.Column = ast.Position(unquote)
+			return node
+		}
+		value := Eval(unquote.Arguments[0], env)
+		if err, ok := value.(*object.Error); ok {
+			failed = err
+			return node
+		}
+		code, err := toAST(value)
+		if err != nil {
+			failed = err
+			failed.Line, failed.Column = ast.Position(unquote)
+			return node
+		}
+		return code
+	})
+	if failed != nil {
+		return failed
+	}
+	return &object.Quote{Node: quoted}
+}
+
+// toAST returns code that evaluates to obj, for unquote
+func toAST(obj object.Object) (ast.Expression, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		literal := strconv.FormatInt(obj.Value, 10)
+		if obj.Big != nil { literal = obj.Big.String() }
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: obj.Value, Big: obj.Big}, nil
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}, nil
+		}
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}, nil
+	case *object.String:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value}, Value: obj.Value}, nil
+	case *object.Quote:
+		if e, ok := obj.Node.(ast.Expression); ok {
+			return e, nil
+		}
+	case *object.Array:
+		array := &ast.ArrayLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "["}, Elements: []ast.Expression{}}
+		for _, element := range obj.Elements {
+			e, err := toAST(element)
+			if err != nil {
+				return nil, err
+			}
+			array.Elements = append(array.Elements, e)
+		}
+		return array, nil
+	case *object.Hash:
+		hash := &ast.HashLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: []ast.HashLiteralPair{}}
+		for _, pair := range obj.Ordered() {
+			key, err := toAST(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := toAST(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
+		}
+		return hash, nil
+	case nil:
+		return nil, newError("cannot unquote NULL")
+	}
+	return nil, newError("cannot unquote %s", obj.Type())
+}
@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+)
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(unquote(9223372036854775807 + 1))`, `9223372036854775808`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`let foo = 8; quote(unquote(foo) + foo)`, `(8 + foo)`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)) * 2)`, `((4 + 4) * 2)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+		{`quote(f(unquote([1, "a"])))`, `f([1, a])`},
+		{`let f = fn() { quote(x) }; f()`, `x`},
+	}
+	for _, tt := range tests {
+		quote, ok := testEval(tt.input).(*object.Quote)
+		if !ok {
+			t.Errorf("%s: expected *object.Quote, got %T", tt.input, testEval(tt.input))
+			continue
+		}
+		if got := quote.Node.String(); got != tt.expected {
+			t.Errorf("%s: got %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let infix = macro() { quote(1 + 2) }; infix()`, `(1 + 2)`},
+		{`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)) }; reverse(2 + 2, 10 - 5)`, `(10 - 5) - (2 + 2)`},
+		{`let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };
+		  unless(10 > 5, puts("not greater"), puts("greater"))`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`},
+		{`let twice = macro(x) { quote([unquote(x), unquote(x)]) }; twice(1); twice(2)`, `[1, 1]; [2, 2]`},
+	}
+	for _, tt := range tests {
+		program := testParse(tt.input)
+		if err := Expand(program, object.NewEnvironment()); err != nil {
+			t.Errorf("%s: %s", tt.input, err.Message)
+			continue
+		}
+		if got, want := program.String(), testParse(tt.expected).String(); got != want {
+			t.Errorf("%s: got %q, want %q", tt.input, got, want)
+		}
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(x) { x + 1 }; m(1)`, "type mismatch: QUOTE + INTEGER"},
+		{`let m = macro(x) { 1 }; m(1)`, "macro m must return a quote, got INTEGER"},
+		{`let m = macro(x) { x }; m()`, "wrong number of arguments: want=1, got=0"},
+		{`quote(unquote(fn() { 1 }))`, "cannot unquote FUNCTION"},
+		{`let f = fn() { macro(x) { x } }; f()`, "macros must be bound with let at the top level of a program"},
+	}
+	for _, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok || err.Message != tt.expected {
+			t.Errorf("%s: got %v, want error %q", tt.input, testEval(tt.input), tt.expected)
+		}
+	}
+}
+
+func testParse(input string) *ast.Program {
+	return parser.New(lexer.New(input)).ParseProgram()
+}
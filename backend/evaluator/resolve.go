@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/object"
+)
+
+// resolver applies the compiler's scoping rules before a program runs, so
+// a name used before its let fails up front with the compiler's message
+// instead of partway through evaluation
+type resolver struct {
+	env    *object.Environment
+	scopes []map[string]bool
+}
+
+// resolve returns an error for the first undefined name in program
+func resolve(program *ast.Program, env *object.Environment) *object.Error {
+	r := &resolver{env: env, scopes: []map[string]bool{{}}}
+	for _, s := range program.Statements {
+		if err := r.node(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *resolver) define(name string) { r.scopes[len(r.scopes)-1][name] = true }
+
+func (r *resolver) defined(name string) bool {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if r.scopes[i][name] {
+			return true
+		}
+	}
+	if _, ok := r.env.Get(name); ok {
+		return true
+	}
+	return object.GetBuiltinByName(name) != nil
+}
+
+func (r *resolver) node(node ast.Node) *object.Error {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		return r.node(node.Expression)
+	case *ast.LetStatement:
+		if err := r.node(node.Value); err != nil {
+			return err
+		}
+		r.define(node.Name.Value)
+	case *ast.ReturnStatement:
+		return r.node(node.ReturnValue)
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := r.node(s); err != nil {
+				return err
+			}
+		}
+	case *ast.Identifier:
+		if !r.defined(node.Value) {
+			err := newError("undefined variable %s", node.Value)
+			err.Line, err.Column = ast.Position(node)
+			return err
+		}
+	case *ast.PrefixExpression:
+		return r.node(node.Right)
+	case *ast.InfixExpression:
+		return r.nodes(node.Left, node.Right)
+	case *ast.IfExpression:
+		if err := r.nodes(node.Condition, node.Consequence); err != nil {
+			return err
+		}
+		if node.Alternative != nil {
+			return r.node(node.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		scope := map[string]bool{}
+		if node.Name != "" {
+			scope[node.Name] = true
+		}
+		for _, p := range node.Parameters {
+			scope[p.Value] = true
+		}
+		r.scopes = append(r.scopes, scope)
+		err := r.node(node.Body)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		return err
+	case *ast.CallExpression:
+		// Quoted code runs wherever a macro puts it
+		if isCallOf(node, "quote") {
+			return nil
+		}
+		if err := r.node(node.Function); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := r.node(a); err != nil {
+				return err
+			}
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			if err := r.node(e); err != nil {
+				return err
+			}
+		}
+	case *ast.IndexExpression:
+		return r.nodes(node.Left, node.Index)
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if err := r.nodes(pair.Key, pair.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) nodes(nodes ...ast.Node) *object.Error {
+	for _, n := range nodes {
+		if err := r.node(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
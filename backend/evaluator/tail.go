@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/object"
+)
+
+// tailCall is a call in tail position, evaluated up to the point of
+// entering the function. applyFunction runs it in place of the call that
+// produced it, so tail-recursive loops run in constant Go stack space. It
+// only travels back to applyFunction (or evalProgram, for a top-level
+// return) and is never seen by Monkey code.
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+	site object.StackFrame
+}
+
+func (t *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (t *tailCall) Inspect() string         { return "tail call" }
+
+// evalTail evaluates node, whose value is returned unchanged by the
+// enclosing function: a function body, the branches of an if in tail
+// position, or the value of a return. These are the calls the compiler
+// emits as OpTailCall.
+func evalTail(node ast.Node, env *object.Environment) object.Object {
+	result := evalTailNode(node, env)
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		locate(err, node, env)
+	}
+	return result
+}
+
+func evalTailNode(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		var result object.Object
+		for i, statement := range node.Statements {
+			if i == len(node.Statements)-1 {
+				return evalTail(statement, env)
+			}
+			result = Eval(statement, env)
+			if _, ok := result.(*object.ReturnValue); ok || isError(result) {
+				return result
+			}
+		}
+		return result
+	case *ast.ExpressionStatement:
+		return evalTail(node.Expression, env)
+	case *ast.IfExpression:
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return orNull(evalTail(node.Consequence, env))
+		}
+		if node.Alternative != nil {
+			return orNull(evalTail(node.Alternative, env))
+		}
+		return NULL
+	case *ast.CallExpression:
+		if isCallOf(node, "quote") {
+			return Eval(node, env)
+		}
+		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		// Builtins and calls that fail run here, in the caller's frame
+		fn, ok := function.(*object.Function)
+		if !ok || len(args) != len(fn.Parameters) {
+			return applyFunction(env.Host(), function, args, callSite(node))
+		}
+		return &tailCall{fn: fn, args: args, site: callSite(node)}
+	}
+	return Eval(node, env)
+}
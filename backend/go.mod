@@ -1,5 +1,3 @@
 module monkey-playground-backend
 
 go 1.22.5
-
-require github.com/NavrajBal/monkey-lang v0.0.0-20250912200937-54dfbdbb35a5 
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
-	"monkey-playground-backend/api"
+	"monkey-playground-backend/web"
 )
 
 func main() {
@@ -16,49 +14,13 @@ func main() {
 		port = "8080"
 	}
 
-	// CORS middleware
-	corsHandler := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
+	// Persist shared snippets to disk when a directory is configured, and
+	// serve the embedded frontend when built with -tags embedui
+	server, err := web.NewServer(web.Config{SnippetDir: os.Getenv("SNIPPET_DIR")})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Routes
-	mux := http.NewServeMux()
-	
-	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	})
-
-	// API routes
-	mux.HandleFunc("/api/tokenize", api.TokenizeHandler)
-	mux.HandleFunc("/api/parse", api.ParseHandler)
-	mux.HandleFunc("/api/compile", api.CompileHandler)
-	mux.HandleFunc("/api/execute", api.ExecuteHandler)
-	mux.HandleFunc("/api/repl", api.ReplHandler)
-
-	// Apply CORS middleware
-	handler := corsHandler(mux)
-
-	fmt.Printf("Server starting on port %s\n", port)
-	fmt.Println("Available endpoints:")
-	fmt.Println("  GET  /health")
-	fmt.Println("  POST /api/tokenize")
-	fmt.Println("  POST /api/parse")
-	fmt.Println("  POST /api/compile")
-	fmt.Println("  POST /api/execute")
-	fmt.Println("  POST /api/repl")
-
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	server.Announce(os.Stdout, port)
+	log.Fatal(http.ListenAndServe(":"+port, server))
 }
@@ -0,0 +1,260 @@
+// Package modules resolves import() calls against a set of source files.
+// The evaluator and the VM share the path rules, caching, cycle detection
+// and diagnostics here and only differ in how a parsed module is run.
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+)
+
+// Extension is appended to import paths that do not name one
+const Extension = ".monkey"
+
+// DefaultEntry names the main program when a request only sends code
+const DefaultEntry = "main" + Extension
+
+// Source supplies module code by normalized path
+type Source interface {
+	Read(name string) (string, error)
+}
+
+// Files is an in-memory file set, as sent by the playground, keyed by
+// normalized path; see NewFiles
+type Files map[string]string
+
+// NewFiles returns files keyed by normalized path, so "./lib/math" and
+// "lib/math.monkey" name the same file. Two names for one file, or a name
+// escaping the file set, are errors.
+func NewFiles(files map[string]string) (Files, error) {
+	normalized := make(Files, len(files))
+	names := make(map[string]string, len(files))
+	for key, code := range files {
+		name, err := Normalize(key)
+		if err != nil {
+			return nil, fmt.Errorf("files[%q]: %v", key, err)
+		}
+		if other, ok := names[name]; ok {
+			if other > key {
+				other, key = key, other
+			}
+			return nil, fmt.Errorf("files[%q] and files[%q] are both %s; send it once", other, key, name)
+		}
+		names[name] = key
+		normalized[name] = code
+	}
+	return normalized, nil
+}
+
+func (f Files) Read(name string) (string, error) {
+	if code, ok := f[name]; ok {
+		return code, nil
+	}
+	return "", fs.ErrNotExist
+}
+
+// Program returns the code of the main program of a request that sends
+// code, files or both: code itself, with files holding the modules it
+// imports, or else the entry file. Giving the entry file as well as code
+// is an error, since it is unclear which one runs, as is a file set that
+// NewFiles rejects.
+func Program(code string, files map[string]string, entry string) (string, error) {
+	normalized, err := NewFiles(files)
+	if err != nil {
+		return "", err
+	}
+	name := NewLoader(nil, entry).Entry()
+	main, err := normalized.Read(name)
+	switch {
+	case code != "" && err == nil:
+		return "", fmt.Errorf("both code and files[%q] hold the main program; send it once", name)
+	case code != "" || len(files) == 0:
+		return code, nil
+	case err != nil:
+		return "", fmt.Errorf("entry file %s is not in files", name)
+	}
+	return main, nil
+}
+
+// Dir reads modules from a directory on disk, as used by the CLI
+type Dir string
+
+func (d Dir) Read(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+	return string(data), err
+}
+
+// Diagnostic is a problem reported against a specific file
+type Diagnostic struct {
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// ImportError is an import() failure, reported as a diagnostic against
+// File. It travels out of the module that failed as the cause of an
+// *object.Error, so the modules importing it pass it on instead of
+// reporting it again.
+type ImportError struct {
+	File    string
+	Message string
+}
+
+func (e *ImportError) Error() string { return e.Message }
+
+// RunFunc executes a parsed module and returns the values of its
+// top-level bindings by name
+type RunFunc func(file string, program *ast.Program) (map[string]object.Object, error)
+
+// Loader evaluates each imported module once per run and caches its exports
+type Loader struct {
+	source      Source
+	run         RunFunc
+	cache       map[string]*object.Hash
+	loading     []string
+	diagnostics []Diagnostic
+}
+
+// NewLoader returns a loader for a program whose main file is entry; run
+// is supplied by the engine through SetRunner
+func NewLoader(source Source, entry string) *Loader {
+	if entry == "" {
+		entry = DefaultEntry
+	}
+	if normalized, err := Normalize(entry); err == nil {
+		entry = normalized
+	}
+	return &Loader{
+		source:  source,
+		cache:   make(map[string]*object.Hash),
+		loading: []string{entry},
+	}
+}
+
+// SetRunner sets how the engine runs a module
+func (l *Loader) SetRunner(run RunFunc) { l.run = run }
+
+// Entry returns the file name of the main program
+func (l *Loader) Entry() string { return l.loading[0] }
+
+// Current returns the file currently being evaluated
+func (l *Loader) Current() string { return l.loading[len(l.loading)-1] }
+
+// Diagnostics returns every import problem reported during the run
+func (l *Loader) Diagnostics() []Diagnostic { return l.diagnostics }
+
+// Report records a diagnostic against file, e.g. a runtime error in the
+// entry program
+func (l *Loader) Report(file, message string) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{File: file, Message: message})
+}
+
+// Import loads the module named by spec and returns its exports hash, or an
+// *object.Error naming the file that failed
+func (l *Loader) Import(spec string) object.Object {
+	importer := l.Current()
+
+	name, err := Normalize(spec)
+	if err != nil {
+		return l.fail(importer, "import %q: %v", spec, err)
+	}
+
+	if exports, ok := l.cache[name]; ok {
+		return exports
+	}
+
+	for i, file := range l.loading {
+		if file == name {
+			chain := append(append([]string{}, l.loading[i:]...), name)
+			return l.fail(importer, "import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	code, err := l.source.Read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l.fail(importer, "import %q: file not found: %s", spec, name)
+	}
+	if err != nil {
+		return l.fail(importer, "import %q: %v", spec, err)
+	}
+
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return l.fail(name, "%s: parse error: %s", name, p.Errors()[0])
+	}
+
+	if l.run == nil {
+		return l.fail(importer, "import %q: no engine attached to the loader", spec)
+	}
+
+	l.loading = append(l.loading, name)
+	bindings, err := l.run(name, program)
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		// Errors from nested imports were reported against their file
+		var failed *ImportError
+		if errors.As(err, &failed) {
+			return &object.Error{Message: failed.Message, Cause: failed}
+		}
+		return l.fail(name, "%s: %v", name, err)
+	}
+
+	exports := exportsHash(bindings)
+	l.cache[name] = exports
+	return exports
+}
+
+func (l *Loader) fail(file, format string, a ...interface{}) *object.Error {
+	failed := &ImportError{File: file, Message: fmt.Sprintf(format, a...)}
+	l.Report(failed.File, failed.Message)
+	return &object.Error{Message: failed.Message, Cause: failed}
+}
+
+// exportsHash lists a module's bindings by name, so its exports print the
+// same way on every run
+func exportsHash(bindings map[string]object.Object) *object.Hash {
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	exports := object.NewHash()
+	for _, name := range names {
+		value := bindings[name]
+		if name == "import" || value == nil {
+			continue
+		}
+		key := &object.String{Value: name}
+		exports.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+	}
+	return exports
+}
+
+// Normalize turns an import path such as "lib/math" or "./lib/math.monkey"
+// into the file name "lib/math.monkey". Paths are relative to the root of
+// the file set and may not escape it.
+func Normalize(spec string) (string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return "", errors.New("empty import path")
+	}
+
+	name := path.Clean(strings.TrimPrefix(spec, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("path escapes the file set: %s", spec)
+	}
+	if path.Ext(name) == "" {
+		name += Extension
+	}
+	return name, nil
+}
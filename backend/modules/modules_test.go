@@ -0,0 +1,172 @@
+package modules_test
+
+import (
+	"strings"
+	"testing"
+
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/vm"
+)
+
+type runner func(files modules.Files) (object.Object, *modules.Loader, error)
+
+func runVM(files modules.Files) (object.Object, *modules.Loader, error) {
+	loader := modules.NewLoader(files, "")
+	program := parser.New(lexer.New(files[modules.DefaultEntry])).ParseProgram()
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return nil, loader, err
+	}
+	machine := vm.New(comp.Bytecode())
+	machine.EnableModules(loader, comp.SymbolTable())
+	if err := machine.Run(); err != nil {
+		return nil, loader, err
+	}
+	return machine.LastPoppedStackElem(), loader, nil
+}
+
+func runEval(files modules.Files) (object.Object, *modules.Loader, error) {
+	loader := modules.NewLoader(files, "")
+	program := parser.New(lexer.New(files[modules.DefaultEntry])).ParseProgram()
+	result := evaluator.Eval(program, evaluator.NewEnvironment(loader, nil))
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, loader, &testError{errObj.Message}
+	}
+	return result, loader, nil
+}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
+
+var engines = map[string]runner{"vm": runVM, "eval": runEval}
+
+func TestImportCachesModule(t *testing.T) {
+	files := modules.Files{
+		"main.monkey": `
+			let a = import("lib/counter");
+			let b = import("./lib/counter.monkey");
+			[a["next"](a["start"]) + b["start"], a == b]`,
+		"lib/counter.monkey": `
+			let start = 10;
+			let next = fn(x) { x + 1 };`,
+	}
+
+	for name, run := range engines {
+		result, _, err := run(files)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if result.Inspect() != "[21, true]" {
+			t.Errorf("%s: result got=%s, want=[21, true]", name, result.Inspect())
+		}
+	}
+}
+
+func TestImportDiagnostics(t *testing.T) {
+	tests := []struct {
+		files   modules.Files
+		file    string
+		message string
+	}{
+		{
+			modules.Files{"main.monkey": `import("missing")`},
+			"main.monkey",
+			"file not found: missing.monkey",
+		},
+		{
+			modules.Files{
+				"main.monkey": `import("a")`,
+				"a.monkey":    `let b = import("b");`,
+				"b.monkey":    `let a = import("a");`,
+			},
+			"b.monkey",
+			"import cycle: a.monkey -> b.monkey -> a.monkey",
+		},
+		{
+			modules.Files{
+				"main.monkey":  `import("a")`,
+				"a.monkey":     `let b = import("lib/b");`,
+				"lib/b.monkey": `let c = import("c");`,
+			},
+			"lib/b.monkey",
+			`import "c": file not found: c.monkey`,
+		},
+		{
+			modules.Files{"main.monkey": `import("../etc/passwd")`},
+			"main.monkey",
+			"escapes the file set",
+		},
+	}
+
+	for name, run := range engines {
+		for _, tt := range tests {
+			_, loader, err := run(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: error got=%v, want %q", name, err, tt.message)
+				continue
+			}
+			diagnostics := loader.Diagnostics()
+			if len(diagnostics) != 1 || diagnostics[0].File != tt.file {
+				t.Errorf("%s: diagnostics got=%+v, want one for %s", name, diagnostics, tt.file)
+			}
+		}
+	}
+}
+
+func TestImportFromTask(t *testing.T) {
+	files := modules.Files{
+		"main.monkey": `
+			let c = chan();
+			let f = fn() { 1 };
+			spawn(fn() { send(c, import("lib")["double"](21)) });
+			let x = recv(c);
+			[x, import("lib")["double"](f())]`,
+		"lib.monkey": `let double = fn(x) { x * 2 };`,
+	}
+
+	for name, run := range engines {
+		result, _, err := run(files)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if result.Inspect() != "[42, 2]" {
+			t.Errorf("%s: result got=%s, want=[42, 2]", name, result.Inspect())
+		}
+	}
+}
+
+func TestProgram(t *testing.T) {
+	files := modules.Files{"main.monkey": "1", "lib/a.monkey": "2"}
+	tests := []struct {
+		code  string
+		files modules.Files
+		entry string
+		want  string
+	}{
+		{"0", nil, "", "0"},
+		{"", files, "", "1"},
+		{"", files, "lib/a", "2"},
+		{"0", modules.Files{"lib/a.monkey": "2"}, "", "0"},
+		{"0", files, "", `error: both code and files["main.monkey"] hold the main program; send it once`},
+		{"", files, "b.monkey", "error: entry file b.monkey is not in files"},
+		{"", modules.Files{"./lib/a": "2"}, "lib/a.monkey", "2"},
+		{"", modules.Files{"main": "1", "/main.monkey": "2"}, "", `error: files["/main.monkey"] and files["main"] are both main.monkey; send it once`},
+		{"", modules.Files{"../a": "1"}, "", `error: files["../a"]: path escapes the file set: ../a`},
+	}
+
+	for _, tt := range tests {
+		got, err := modules.Program(tt.code, tt.files, tt.entry)
+		if err != nil {
+			got = "error: " + err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("Program(%q, %v, %q) = %q, want %q", tt.code, tt.files, tt.entry, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package object
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Builtins lists builtin functions available to programs. The VM refers to
+// them by index, so new builtins go at the end.
+var Builtins = []*BuiltinDefinition{
+    define(BuiltinDefinition{Name: "len", Params: []Param{arg("x", STRING_OBJ, ARRAY_OBJ)}, Fn: builtinLen,
+        Description: "Length of a string (in bytes) or array", Examples: []string{`len("monkey") // 6`, `len([1, 2]) // 2`}}),
+    define(BuiltinDefinition{Name: "puts", Params: []Param{variadic("args")}, Fn: builtinPuts,
+        Description: "Prints each argument on its own line and returns null", Examples: []string{`puts("hello") // null`}}),
+    define(BuiltinDefinition{Name: "first", Params: []Param{arg("a", ARRAY_OBJ)}, Fn: builtinFirst,
+        Description: "First element of an array, or null when it is empty", Examples: []string{`first([1, 2, 3]) // 1`}}),
+    define(BuiltinDefinition{Name: "last", Params: []Param{arg("a", ARRAY_OBJ)}, Fn: builtinLast,
+        Description: "Last element of an array, or null when it is empty", Examples: []string{`last([1, 2, 3]) // 3`}}),
+    define(BuiltinDefinition{Name: "rest", Params: []Param{arg("a", ARRAY_OBJ)}, Fn: builtinRest,
+        Description: "New array without the first element, or null when it is empty", Examples: []string{`rest([1, 2, 3]) // [2, 3]`}}),
+    define(BuiltinDefinition{Name: "push", Params: []Param{arg("a", ARRAY_OBJ), arg("x")}, Fn: builtinPush,
+        Description: "New array with x appended", Examples: []string{`push([1, 2], 3) // [1, 2, 3]`}}),
+    define(BuiltinDefinition{Name: "import", Params: []Param{arg("path", STRING_OBJ)}, Fn: builtinImport,
+        Description: "Hash of a module's top-level bindings"}),
+    define(BuiltinDefinition{Name: "host", Params: []Param{arg("name", STRING_OBJ), variadic("args")}, Fn: builtinHost,
+        Description: "Calls a JavaScript function granted to the run (WASM only)"}),
+    define(BuiltinDefinition{Name: "split", Params: []Param{arg("s", STRING_OBJ), arg("sep", STRING_OBJ)}, Fn: builtinSplit,
+        Description: "Array of the parts of s between each sep. An empty sep splits into characters.", Examples: []string{`split("a,b,c", ",") // [a, b, c]`}}),
+    define(BuiltinDefinition{Name: "join", Params: []Param{arg("a", ARRAY_OBJ), arg("sep", STRING_OBJ)}, Fn: builtinJoin,
+        Description: "Elements of a joined with sep. Non-strings are joined as they print.", Examples: []string{`join([1, 2, 3], "-") // 1-2-3`}}),
+    define(BuiltinDefinition{Name: "substr", Params: []Param{arg("s", STRING_OBJ), arg("start", INTEGER_OBJ), optional("length", INTEGER_OBJ)}, Fn: builtinSubstr,
+        Description: "Part of s from start, to the end or for length bytes. A negative start counts from the end, and out-of-range bounds are clamped.",
+        Examples: []string{`substr("monkey", 1, 3) // onk`, `substr("monkey", -3) // key`}}),
+    define(BuiltinDefinition{Name: "index_of", Params: []Param{arg("s", STRING_OBJ, ARRAY_OBJ), arg("x")}, Fn: builtinIndexOf,
+        Description: "Byte index of the first x in the string s, or -1. On an array, the index of the first element equal to x.",
+        Examples: []string{`index_of("monkey", "key") // 3`, `index_of([1, 2], 3) // -1`}}),
+    define(BuiltinDefinition{Name: "contains", Params: []Param{arg("s", STRING_OBJ, ARRAY_OBJ), arg("x")}, Fn: builtinContains,
+        Description: "Whether the string s contains x, or whether an array contains the value x", Examples: []string{`contains([1, 2], 2) // true`}}),
+    define(BuiltinDefinition{Name: "upper", Params: []Param{arg("s", STRING_OBJ)}, Fn: stringFunction(strings.ToUpper),
+        Description: "s in upper case", Examples: []string{`upper("monkey") // MONKEY`}}),
+    define(BuiltinDefinition{Name: "lower", Params: []Param{arg("s", STRING_OBJ)}, Fn: stringFunction(strings.ToLower),
+        Description: "s in lower case", Examples: []string{`lower("Monkey") // monkey`}}),
+    define(BuiltinDefinition{Name: "trim", Params: []Param{arg("s", STRING_OBJ)}, Fn: stringFunction(strings.TrimSpace),
+        Description: "s without leading and trailing whitespace", Examples: []string{`trim("  monkey ") // monkey`}}),
+    define(BuiltinDefinition{Name: "replace", Params: []Param{arg("s", STRING_OBJ), arg("old", STRING_OBJ), arg("new", STRING_OBJ)}, Fn: builtinReplace,
+        Description: "s with every old replaced by new", Examples: []string{`replace("a-b-c", "-", "+") // a+b+c`}}),
+    define(BuiltinDefinition{Name: "chars", Params: []Param{arg("s", STRING_OBJ)}, Fn: builtinChars,
+        Description: "Array of the characters of s", Examples: []string{`chars("abc") // [a, b, c]`}}),
+    define(BuiltinDefinition{Name: "repeat", Params: []Param{arg("s", STRING_OBJ), arg("n", INTEGER_OBJ)}, Fn: builtinRepeat,
+        Description: "s repeated n times, up to 16 MB", Examples: []string{`repeat("ab", 3) // ababab`}}),
+    define(BuiltinDefinition{Name: "to_string", Params: []Param{arg("x")}, Fn: builtinToString,
+        Description: "x as it prints", Examples: []string{`to_string([1, 2]) // [1, 2]`}}),
+    define(BuiltinDefinition{Name: "to_int", Params: []Param{arg("x", STRING_OBJ, INTEGER_OBJ)}, Fn: builtinToInt,
+        Description: "Integer parsed from a decimal string (surrounding spaces allowed), or x itself if it is already an integer",
+        Examples: []string{`to_int(" 42 ") // 42`, `to_int("18446744073709551616") // 18446744073709551616`}}),
+    define(BuiltinDefinition{Name: "map", Params: []Param{arg("a", ARRAY_OBJ), arg("f", FUNCTION_OBJ)}, Fn: builtinMap,
+        Description: "Array of f(x) for each element x", Examples: []string{`map([1, 2, 3], fn(x) { x * 2 }) // [2, 4, 6]`}}),
+    define(BuiltinDefinition{Name: "filter", Params: []Param{arg("a", ARRAY_OBJ), arg("f", FUNCTION_OBJ)}, Fn: builtinFilter,
+        Description: "Array of the elements for which f(x) is truthy", Examples: []string{`filter([1, 2, 3], fn(x) { x > 1 }) // [2, 3]`}}),
+    define(BuiltinDefinition{Name: "reduce", Params: []Param{arg("a", ARRAY_OBJ), arg("initial"), arg("f", FUNCTION_OBJ)}, Fn: builtinReduce,
+        Description: "Folds a from the left with f(acc, x), starting from initial", Examples: []string{`reduce([1, 2, 3], 0, fn(acc, x) { acc + x }) // 6`}}),
+    define(BuiltinDefinition{Name: "sort", Params: []Param{arg("a", ARRAY_OBJ), optional("less", FUNCTION_OBJ)}, Fn: builtinSort,
+        Description: "Sorted copy of an array of integers or strings, or of anything with a less(a, b) comparator. The sort is stable.",
+        Examples: []string{`sort([3, 1, 2]) // [1, 2, 3]`, `sort([1, 2, 3], fn(a, b) { a > b }) // [3, 2, 1]`}}),
+    define(BuiltinDefinition{Name: "reverse", Params: []Param{arg("a", ARRAY_OBJ)}, Fn: builtinReverse,
+        Description: "Array in reverse order", Examples: []string{`reverse([1, 2, 3]) // [3, 2, 1]`}}),
+    define(BuiltinDefinition{Name: "range", Params: []Param{arg("start", INTEGER_OBJ), optional("end", INTEGER_OBJ), optional("step", INTEGER_OBJ)}, Fn: builtinRange,
+        Description: "Integers from start up to but not including end. Given one argument, the integers from 0 up to it.",
+        Examples: []string{`range(3) // [0, 1, 2]`, `range(1, 10, 4) // [1, 5, 9]`}}),
+    define(BuiltinDefinition{Name: "slice", Params: []Param{arg("a", ARRAY_OBJ), arg("start", INTEGER_OBJ), optional("end", INTEGER_OBJ)}, Fn: builtinSlice,
+        Description: "Elements from start up to but not including end, with bounds handled as in substr", Examples: []string{`slice([1, 2, 3, 4], 1, 3) // [2, 3]`}}),
+    define(BuiltinDefinition{Name: "concat", Params: []Param{variadic("arrays", ARRAY_OBJ)}, Fn: builtinConcat,
+        Description: "One array with the elements of each argument", Examples: []string{`concat([1], [2, 3]) // [1, 2, 3]`}}),
+    define(BuiltinDefinition{Name: "zip", Params: []Param{arg("a", ARRAY_OBJ), arg("b", ARRAY_OBJ)}, Fn: builtinZip,
+        Description: "Array of [a[i], b[i]] pairs, as long as the shorter array", Examples: []string{`zip([1, 2], ["a", "b"]) // [[1, a], [2, b]]`}}),
+    define(BuiltinDefinition{Name: "keys", Params: []Param{arg("h", HASH_OBJ)}, Fn: hashElements(func(pair HashPair) Object { return pair.Key }),
+        Description: "Array of a hash's keys, in insertion order", Examples: []string{`keys({"b": 2, "a": 1}) // [b, a]`}}),
+    define(BuiltinDefinition{Name: "values", Params: []Param{arg("h", HASH_OBJ)}, Fn: hashElements(func(pair HashPair) Object { return pair.Value }),
+        Description: "Array of a hash's values, in insertion order", Examples: []string{`values({"b": 2, "a": 1}) // [2, 1]`}}),
+    define(BuiltinDefinition{Name: "entries", Params: []Param{arg("h", HASH_OBJ)}, Fn: hashElements(func(pair HashPair) Object { return &Array{Elements: []Object{pair.Key, pair.Value}} }),
+        Description: "Array of a hash's [key, value] pairs", Examples: []string{`entries({"a": 1}) // [[a, 1]]`}}),
+    define(BuiltinDefinition{Name: "has", Params: []Param{arg("h", HASH_OBJ), arg("key")}, Fn: builtinHas,
+        Description: "Whether h has key", Examples: []string{`has({"a": 1}, "a") // true`}}),
+    define(BuiltinDefinition{Name: "delete", Params: []Param{arg("h", HASH_OBJ), arg("key")}, Fn: builtinDelete,
+        Description: "New hash without key", Examples: []string{`delete({"a": 1, "b": 2}, "a") // {b: 2}`}}),
+    define(BuiltinDefinition{Name: "merge", Params: []Param{variadic("hashes", HASH_OBJ)}, Fn: builtinMerge,
+        Description: "New hash with the pairs of every argument. Later hashes win on duplicate keys.", Examples: []string{`merge({"a": 1}, {"a": 2, "b": 3}) // {a: 2, b: 3}`}}),
+    define(BuiltinDefinition{Name: "error", Params: []Param{arg("payload")}, Fn: builtinError,
+        Description: "Raises a runtime error. The message is payload if it is a string, or payload as it prints; try hands the payload itself to its handler.",
+        Examples: []string{`try(fn() { error({"code": 404}) }, fn(e) { e["payload"]["code"] }) // 404`}}),
+    define(BuiltinDefinition{Name: "try", Params: []Param{arg("f", FUNCTION_OBJ), arg("handler", FUNCTION_OBJ)}, Fn: builtinTry,
+        Description: "Calls f and returns its result. If f fails with a runtime error, returns handler(e) instead, where e is a hash of the error's message, payload, line, column and stack.",
+        Examples: []string{`try(fn() { 1 / 0 }, fn(e) { e["message"] }) // division by zero`}}),
+    define(BuiltinDefinition{Name: "spawn", Params: []Param{arg("f", FUNCTION_OBJ), variadic("args")}, Fn: builtinSpawn,
+        Description: "Runs f(args...) as a new task. It starts once the current task blocks or finishes; an error in it fails the whole run.",
+        Examples: []string{`let c = chan(); spawn(fn(n) { send(c, n * 2) }, 21); recv(c) // 42`}}),
+    define(BuiltinDefinition{Name: "chan", Params: []Param{optional("capacity", INTEGER_OBJ)}, Fn: builtinChan,
+        Description: "New channel buffering up to capacity values (default 0, so each send waits for a receiver)",
+        Examples: []string{`let c = chan(1); send(c, "hi"); recv(c) // hi`}}),
+    define(BuiltinDefinition{Name: "send", Params: []Param{arg("ch", CHANNEL_OBJ), arg("value")}, Fn: builtinSend,
+        Description: "Sends value on ch, waiting while its buffer is full. Fails if ch is closed.",
+        Examples: []string{`let c = chan(2); send(c, 1); send(c, 2); [recv(c), recv(c)] // [1, 2]`}}),
+    define(BuiltinDefinition{Name: "recv", Params: []Param{arg("ch", CHANNEL_OBJ)}, Fn: builtinRecv,
+        Description: "Next value sent on ch, waiting for one if needed; null once ch is closed and empty",
+        Examples: []string{`let c = chan(); spawn(fn() { send(c, "done") }); recv(c) // done`}}),
+    define(BuiltinDefinition{Name: "close", Params: []Param{arg("ch", CHANNEL_OBJ)}, Fn: builtinClose,
+        Description: "Closes ch. Values already sent can still be received.",
+        Examples: []string{`let c = chan(1); send(c, 1); close(c); [recv(c), recv(c)] // [1, null]`}}),
+}
+
+// arg, optional and variadic declare builtin parameters; with no types
+// they accept any value
+func arg(name string, types ...ObjectType) Param { return Param{Name: name, Types: types} }
+func optional(name string, types ...ObjectType) Param { return Param{Name: name, Types: types, Optional: true} }
+func variadic(name string, types ...ObjectType) Param { return Param{Name: name, Types: types, Variadic: true} }
+
+func builtinLen(host Host, args ...Object) Object {
+    if arr, ok := args[0].(*Array); ok { return &Integer{Value: int64(len(arr.Elements))} }
+    return &Integer{Value: int64(len(args[0].(*String).Value))}
+}
+
+func builtinPuts(host Host, args ...Object) Object { out := Output(host); for _, arg := range args { fmt.Fprintln(out, arg.Inspect()) }; return nil }
+
+func builtinFirst(host Host, args ...Object) Object {
+    arr := args[0].(*Array)
+    if len(arr.Elements) > 0 { return arr.Elements[0] }
+    return nil
+}
+
+func builtinLast(host Host, args ...Object) Object {
+    arr := args[0].(*Array)
+    if l := len(arr.Elements); l > 0 { return arr.Elements[l-1] }
+    return nil
+}
+
+func builtinRest(host Host, args ...Object) Object {
+    arr := args[0].(*Array)
+    if l := len(arr.Elements); l > 0 { newElements := make([]Object, l-1, l-1); copy(newElements, arr.Elements[1:l]); return &Array{Elements: newElements} }
+    return nil
+}
+
+func builtinPush(host Host, args ...Object) Object {
+    arr := args[0].(*Array)
+    l := len(arr.Elements)
+    newElements := make([]Object, l+1, l+1); copy(newElements, arr.Elements); newElements[l] = args[1]
+    return &Array{Elements: newElements}
+}
+
+func builtinImport(host Host, args ...Object) Object {
+    if host == nil { return newError("import is not available outside a program run") }
+    return host.Import(args[0].(*String).Value)
+}
+
+func builtinHost(host Host, args ...Object) Object {
+    name := args[0].(*String).Value
+    var fn HostFunction
+    if host != nil { fn = host.HostFunction(name) }
+    if fn == nil { return newError("host function %q is not available", name) }
+    return fn(args[1:]...)
+}
+
+func newError(format string, a ...interface{}) *Error { return &Error{Message: fmt.Sprintf(format, a...)} }
+
+// GetBuiltinByName finds a builtin by name
+func GetBuiltinByName(name string) *Builtin {
+    for _, def := range Builtins { if def.Name == name { return def.Builtin } }
+    return nil
+}
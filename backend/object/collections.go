@@ -0,0 +1,264 @@
+package object
+
+import "sort"
+
+// maxRange bounds the arrays range builds
+const maxRange = 1 << 24
+
+// Array and hash builtins. They never modify their arguments: like push,
+// each returns a new array or hash. Builtins taking a function call it
+// through the host, so Monkey callbacks run on the engine running the
+// program, and a failing callback ends the builtin with its error.
+
+// apply calls fn, which must be a function or builtin, through host
+func apply(host Host, fn Object, args ...Object) Object {
+	if host == nil {
+		return newError("functions cannot be called outside a program run")
+	}
+	return host.Apply(fn, args...)
+}
+
+func builtinMap(host Host, args ...Object) Object {
+	elements := args[0].(*Array).Elements
+	mapped := make([]Object, len(elements))
+	for i, e := range elements {
+		result := apply(host, args[1], e)
+		if isError(result) {
+			return result
+		}
+		mapped[i] = result
+	}
+	return &Array{Elements: mapped}
+}
+
+func builtinFilter(host Host, args ...Object) Object {
+	kept := []Object{}
+	for _, e := range args[0].(*Array).Elements {
+		result := apply(host, args[1], e)
+		if isError(result) {
+			return result
+		}
+		if truthy(result) {
+			kept = append(kept, e)
+		}
+	}
+	return &Array{Elements: kept}
+}
+
+// builtinReduce folds the array from the left: reduce(a, initial,
+// fn(acc, x))
+func builtinReduce(host Host, args ...Object) Object {
+	acc := args[1]
+	for _, e := range args[0].(*Array).Elements {
+		acc = apply(host, args[2], acc, e)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// builtinSort sorts integers or strings in ascending order, or anything
+// with a comparator fn(a, b) that returns true when a belongs before b.
+// The sort is stable.
+func builtinSort(host Host, args ...Object) Object {
+	sorted := append([]Object{}, args[0].(*Array).Elements...)
+	var failed Object
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+		var less bool
+		if len(args) == 2 {
+			result := apply(host, args[1], sorted[i], sorted[j])
+			if isError(result) {
+				failed = result
+				return false
+			}
+			less = truthy(result)
+		} else {
+			less, failed = compare(sorted[i], sorted[j])
+		}
+		return less
+	})
+	if failed != nil {
+		return failed
+	}
+	return &Array{Elements: sorted}
+}
+
+// compare orders two integers or two strings for sort
+func compare(a, b Object) (bool, Object) {
+	switch a := a.(type) {
+	case *Integer:
+		if b, ok := b.(*Integer); ok {
+			return CompareIntegers(a, b) < 0, nil
+		}
+	case *String:
+		if b, ok := b.(*String); ok {
+			return a.Value < b.Value, nil
+		}
+	}
+	return false, newError("`sort` cannot compare %s with %s without a comparator", a.Type(), b.Type())
+}
+
+func builtinReverse(host Host, args ...Object) Object {
+	elements := args[0].(*Array).Elements
+	reversed := make([]Object, len(elements))
+	for i, e := range elements {
+		reversed[len(elements)-1-i] = e
+	}
+	return &Array{Elements: reversed}
+}
+
+// builtinRange returns the integers from start up to, not including, end:
+// range(end), range(start, end) or range(start, end, step)
+func builtinRange(host Host, args ...Object) Object {
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		bounds[i] = arg.(*Integer).Value
+	}
+
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return newError("step given to `range` must not be zero")
+	}
+
+	var count int64
+	if step > 0 && end > start {
+		count = (end - start + step - 1) / step
+	} else if step < 0 && end < start {
+		count = (start - end - step - 1) / -step
+	}
+	// A count below zero means end - start overflowed
+	if count > maxRange || count < 0 {
+		return newError("result of `range` is too long (limit %d elements)", maxRange)
+	}
+	elements := make([]Object, count)
+	for i := range elements {
+		elements[i] = &Integer{Value: start + int64(i)*step}
+	}
+	return &Array{Elements: elements}
+}
+
+// builtinSlice returns the elements from start up to, not including, end
+// (the end of the array when omitted); negative bounds count from the end
+// and both are clamped, as in substr
+func builtinSlice(host Host, args ...Object) Object {
+	elements := args[0].(*Array).Elements
+	n := int64(len(elements))
+	bound := func(arg Object) int64 {
+		i := arg.(*Integer).Value
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, 0), n)
+	}
+	start, end := bound(args[1]), n
+	if len(args) == 3 {
+		end = bound(args[2])
+	}
+	if end < start {
+		end = start
+	}
+	return &Array{Elements: append([]Object{}, elements[start:end]...)}
+}
+
+func builtinConcat(host Host, args ...Object) Object {
+	joined := []Object{}
+	for _, arg := range args {
+		joined = append(joined, arg.(*Array).Elements...)
+	}
+	return &Array{Elements: joined}
+}
+
+// builtinZip pairs up the elements of two arrays, stopping at the end of
+// the shorter one
+func builtinZip(host Host, args ...Object) Object {
+	a, b := args[0].(*Array).Elements, args[1].(*Array).Elements
+	pairs := make([]Object, min(len(a), len(b)))
+	for i := range pairs {
+		pairs[i] = &Array{Elements: []Object{a[i], b[i]}}
+	}
+	return &Array{Elements: pairs}
+}
+
+// hashElements makes a builtin listing something about each pair of its
+// HASH argument, in insertion order
+func hashElements(element func(HashPair) Object) BuiltinFunction {
+	return func(host Host, args ...Object) Object {
+		pairs := args[0].(*Hash).Ordered()
+		elements := make([]Object, len(pairs))
+		for i, pair := range pairs {
+			elements[i] = element(pair)
+		}
+		return &Array{Elements: elements}
+	}
+}
+
+// hashKey returns the hash and key arguments of has and delete
+func hashKey(args []Object) (*Hash, HashKey, *Error) {
+	key, ok := HashKeyOf(args[1])
+	if !ok {
+		return nil, HashKey{}, newError("unusable as hash key: %s", args[1].Type())
+	}
+	return args[0].(*Hash), key, nil
+}
+
+func builtinHas(host Host, args ...Object) Object {
+	hash, key, err := hashKey(args)
+	if err != nil {
+		return err
+	}
+	_, ok := hash.Pairs[key]
+	return &Boolean{Value: ok}
+}
+
+func builtinDelete(host Host, args ...Object) Object {
+	hash, key, err := hashKey(args)
+	if err != nil {
+		return err
+	}
+	deleted := NewHash()
+	for _, k := range hash.Keys {
+		if k != key {
+			deleted.Set(k, hash.Pairs[k])
+		}
+	}
+	return deleted
+}
+
+// builtinMerge combines hashes; later hashes win on duplicate keys, which
+// keep the position they first appeared at
+func builtinMerge(host Host, args ...Object) Object {
+	merged := NewHash()
+	for _, arg := range args {
+		hash := arg.(*Hash)
+		for _, k := range hash.Keys {
+			merged.Set(k, hash.Pairs[k])
+		}
+	}
+	return merged
+}
+
+func truthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case nil, *Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
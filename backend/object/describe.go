@@ -0,0 +1,117 @@
+package object
+
+// Bounds on the tree Describe builds, so a huge or deeply nested value
+// cannot blow up a response
+const (
+	DescribeMaxElements = 100
+	DescribeMaxDepth    = 16
+	DescribeMaxString   = 4096
+)
+
+// maxSafeInteger is the largest integer a JavaScript number holds exactly
+const maxSafeInteger = 1<<53 - 1
+
+// Describe returns obj as a typed tree for clients that render results,
+// such as the playground's value view. Every node has a "type" of
+// "integer", "boolean", "string", "null", "array", "hash", "function",
+// "builtin" or "channel":
+//
+//   - scalars carry "value"; integers beyond JavaScript's safe range are
+//     given as decimal strings
+//   - arrays carry "length" and "elements", hashes "length" and "pairs"
+//     ({"key", "value"} in insertion order, as Inspect prints them)
+//   - functions carry "name" (when bound with let), "parameters" and
+//     "arity"; builtins carry "name"
+//   - channels carry "capacity"
+//
+// Nodes cut short by the Describe bounds carry "truncated": true, and
+// strings then also their full "length".
+func Describe(obj Object) map[string]any {
+	return describe(obj, 0)
+}
+
+func describe(obj Object, depth int) map[string]any {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return map[string]any{"type": "null"}
+	case *Integer:
+		node := map[string]any{"type": "integer", "value": obj.Value}
+		if obj.Big != nil || obj.Value > maxSafeInteger || obj.Value < -maxSafeInteger {
+			node["value"] = obj.Inspect()
+		}
+		return node
+	case *Boolean:
+		return map[string]any{"type": "boolean", "value": obj.Value}
+	case *String:
+		node := map[string]any{"type": "string", "value": obj.Value}
+		if len(obj.Value) > DescribeMaxString {
+			node["value"] = obj.Value[:DescribeMaxString]
+			node["length"] = len(obj.Value)
+			node["truncated"] = true
+		}
+		return node
+	case *Array:
+		node := map[string]any{"type": "array", "length": len(obj.Elements)}
+		elements := []any{}
+		for i, element := range obj.Elements {
+			if depth >= DescribeMaxDepth || i == DescribeMaxElements {
+				node["truncated"] = true
+				break
+			}
+			elements = append(elements, describe(element, depth+1))
+		}
+		node["elements"] = elements
+		return node
+	case *Hash:
+		node := map[string]any{"type": "hash", "length": len(obj.Pairs)}
+		pairs := []any{}
+		for i, pair := range obj.Ordered() {
+			if depth >= DescribeMaxDepth || i == DescribeMaxElements {
+				node["truncated"] = true
+				break
+			}
+			pairs = append(pairs, map[string]any{
+				"key":   describe(pair.Key, depth+1),
+				"value": describe(pair.Value, depth+1),
+			})
+		}
+		node["pairs"] = pairs
+		return node
+	case *Function:
+		params := make([]string, len(obj.Parameters))
+		for i, p := range obj.Parameters {
+			params[i] = p.Value
+		}
+		return describeFunction(obj.Name, params)
+	case *Closure:
+		return describeFunction(obj.Fn.Name, obj.Fn.Parameters)
+	case *CompiledFunction:
+		return describeFunction(obj.Name, obj.Parameters)
+	case *Builtin:
+		node := map[string]any{"type": "builtin"}
+		for _, def := range Builtins {
+			if def.Builtin == obj {
+				node["name"] = def.Name
+			}
+		}
+		return node
+	case *Channel:
+		return map[string]any{"type": "channel", "capacity": obj.Capacity}
+	default:
+		return map[string]any{"type": string(obj.Type()), "value": obj.Inspect()}
+	}
+}
+
+// describeFunction lists parameters as []any, like the other nodes, so
+// the tree also converts with syscall/js.ValueOf
+func describeFunction(name string, params []string) map[string]any {
+	parameters := make([]any, len(params))
+	for i, p := range params {
+		parameters[i] = p
+	}
+	node := map[string]any{"type": "function", "parameters": parameters, "arity": len(params)}
+	if name != "" {
+		node["name"] = name
+	}
+	return node
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	env.outerDefs = math.MaxInt
+	env.host = outer.host
+	return env
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]binding)
+	return &Environment{store: s, outer: nil}
+}
+
+// Environment holds the bindings of one scope. Tasks running in parallel
+// share the scopes their functions close over, so mu guards store.
+type Environment struct {
+	mu    sync.RWMutex
+	store map[string]binding
+	defs  int
+	outer *Environment
+	// outerDefs is how many of outer's bindings this scope sees; see Snapshot
+	outerDefs int
+	host      Host
+}
+
+// binding is the value of a name; a later let of the same name in the
+// scope shadows it rather than replacing it
+type binding struct {
+	value Object
+	def   int // the scope's definition count once this binding was made
+	prev  *binding
+}
+
+// Snapshot returns an empty scope over e that sees only the bindings e
+// holds now, like a compiled closure: names e defines or rebinds later
+// stay invisible to functions created in it
+func (e *Environment) Snapshot() *Environment {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return &Environment{outer: e, outerDefs: e.defs, host: e.host}
+}
+
+// Host returns the engine host shared by this environment and every
+// environment enclosed by it
+func (e *Environment) Host() Host { return e.host }
+
+// SetHost attaches h to the environment; set it on the root environment
+// before evaluation so functions and enclosed scopes inherit it
+func (e *Environment) SetHost(h Host) { e.host = h }
+
+// Names returns the bindings defined directly in this environment, sorted
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.store))
+	for name := range e.store { names = append(names, name) }
+	e.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	for env, limit := e, math.MaxInt; env != nil; env, limit = env.outer, env.outerDefs {
+		if obj, ok := env.lookup(name, limit); ok { return obj, true }
+	}
+	return nil, false
+}
+
+// lookup finds the latest binding of name among the first limit made here
+func (e *Environment) lookup(name string, limit int) (Object, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	b, ok := e.store[name]
+	for ok && b.def > limit {
+		if b.prev == nil { return nil, false }
+		b = *b.prev
+	}
+	return b.value, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
+	if e.store == nil { e.store = make(map[string]binding) }
+	e.defs++
+	b := binding{value: val, def: e.defs}
+	if old, ok := e.store[name]; ok { b.prev = &old }
+	e.store[name] = b
+	e.mu.Unlock()
+	return val
+}
+
+
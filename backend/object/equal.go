@@ -0,0 +1,72 @@
+package object
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Equal implements == for both engines: integers, strings, booleans and
+// null compare by value, arrays element by element and hashes pair by
+// pair regardless of order. Functions and builtins compare by identity.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && CompareIntegers(a, b) == 0
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i, element := range a.Elements {
+			if !Equal(element, b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !Equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// HashKeyOf returns the key obj is stored under in a hash, and false when
+// obj is unusable as a key. Arrays are usable when their elements are,
+// and equal arrays share a key.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	switch obj := obj.(type) {
+	case Hashable:
+		return obj.HashKey(), true
+	case *Array:
+		h := fnv.New64a()
+		for _, element := range obj.Elements {
+			key, ok := HashKeyOf(element)
+			if !ok {
+				return HashKey{}, false
+			}
+			h.Write([]byte(key.Type))
+			binary.Write(h, binary.LittleEndian, key.Value)
+		}
+		return HashKey{Type: ARRAY_OBJ, Value: h.Sum64()}, true
+	}
+	return HashKey{}, false
+}
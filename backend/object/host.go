@@ -0,0 +1,70 @@
+package object
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Host is implemented by the engines (evaluator and VM) that run builtins.
+// It exposes the per-run state a builtin may need.
+type Host interface {
+	// Import evaluates the module at path once per run and returns its
+	// exports hash, or an *Error describing why it could not be loaded
+	Import(path string) Object
+
+	// Output is where puts writes for this run
+	Output() io.Writer
+
+	// HostFunction returns the embedder function granted to this run
+	// under name, or nil
+	HostFunction(name string) HostFunction
+
+	// Apply calls a Monkey function or builtin with args, letting builtins
+	// such as map call back into the program. Failures come back as an
+	// *Error.
+	Apply(fn Object, args ...Object) Object
+
+	// Spawn starts fn(args...) as a new task of this run
+	Spawn(fn Object, args ...Object) *Error
+
+	// Task returns the task running on this host, creating the run's
+	// scheduler on first use
+	Task() *Task
+}
+
+// HostFunction is a function the embedding program grants a run. Monkey
+// code calls it with host(name, args...); returning nil yields null.
+type HostFunction func(args ...Object) Object
+
+// HostFunctions are the capabilities granted to a run, by name. Runs get
+// none unless the embedder sets them.
+type HostFunctions map[string]HostFunction
+
+// Output returns host's writer, or os.Stdout when no host is running
+func Output(host Host) io.Writer {
+	if host == nil {
+		return os.Stdout
+	}
+	return host.Output()
+}
+
+// SharedOutput returns w made safe for the tasks of a run to write to at
+// once
+func SharedOutput(w io.Writer) io.Writer {
+	if _, ok := w.(*sharedOutput); ok {
+		return w
+	}
+	return &sharedOutput{w: w}
+}
+
+type sharedOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (o *sharedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Write(p)
+}
@@ -0,0 +1,102 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Integers are int64s until arithmetic overflows, when they promote to
+// big integers: Big then holds the exact value and Value is clamped to the
+// int64 limit of the same sign. Code that only needs an int64, such as an
+// index or a count, can keep reading Value and sees a number that is out
+// of range in the right direction. Results that fit an int64 again drop
+// back to the small form, so two equal integers always have the same form.
+
+// NewBigInteger returns n as an Integer in its normal form
+func NewBigInteger(n *big.Int) *Integer {
+	if n.IsInt64() {
+		return &Integer{Value: n.Int64()}
+	}
+	if n.Sign() > 0 {
+		return &Integer{Value: math.MaxInt64, Big: n}
+	}
+	return &Integer{Value: math.MinInt64, Big: n}
+}
+
+// BigValue returns i's value as a new big.Int
+func (i *Integer) BigValue() *big.Int {
+	if i.Big != nil {
+		return new(big.Int).Set(i.Big)
+	}
+	return big.NewInt(i.Value)
+}
+
+// AddIntegers, SubIntegers and MulIntegers implement +, - and * for both
+// engines, promoting to a big integer when the int64 result overflows
+func AddIntegers(a, b *Integer) *Integer {
+	if a.Big == nil && b.Big == nil {
+		sum := a.Value + b.Value
+		if (sum > a.Value) == (b.Value > 0) {
+			return &Integer{Value: sum}
+		}
+	}
+	return NewBigInteger(new(big.Int).Add(a.BigValue(), b.BigValue()))
+}
+
+func SubIntegers(a, b *Integer) *Integer {
+	if a.Big == nil && b.Big == nil {
+		diff := a.Value - b.Value
+		if (diff < a.Value) == (b.Value > 0) {
+			return &Integer{Value: diff}
+		}
+	}
+	return NewBigInteger(new(big.Int).Sub(a.BigValue(), b.BigValue()))
+}
+
+func MulIntegers(a, b *Integer) *Integer {
+	if a.Big == nil && b.Big == nil {
+		if a.Value == 0 || b.Value == 0 {
+			return &Integer{Value: 0}
+		}
+		product := a.Value * b.Value
+		if product/b.Value == a.Value && !(a.Value == -1 && b.Value == math.MinInt64) && !(b.Value == -1 && a.Value == math.MinInt64) {
+			return &Integer{Value: product}
+		}
+	}
+	return NewBigInteger(new(big.Int).Mul(a.BigValue(), b.BigValue()))
+}
+
+// DivIntegers implements / for both engines, truncating toward zero. It
+// returns an error when b is zero.
+func DivIntegers(a, b *Integer) (*Integer, *Error) {
+	if b.Big == nil && b.Value == 0 {
+		return nil, newError("division by zero")
+	}
+	if a.Big == nil && b.Big == nil && !(a.Value == math.MinInt64 && b.Value == -1) {
+		return &Integer{Value: a.Value / b.Value}, nil
+	}
+	return NewBigInteger(new(big.Int).Quo(a.BigValue(), b.BigValue())), nil
+}
+
+// NegateInteger implements unary minus
+func NegateInteger(i *Integer) *Integer {
+	if i.Big == nil && i.Value != math.MinInt64 {
+		return &Integer{Value: -i.Value}
+	}
+	return NewBigInteger(new(big.Int).Neg(i.BigValue()))
+}
+
+// CompareIntegers returns -1, 0 or +1 as a is less than, equal to or
+// greater than b
+func CompareIntegers(a, b *Integer) int {
+	if a.Big == nil && b.Big == nil {
+		switch {
+		case a.Value < b.Value:
+			return -1
+		case a.Value > b.Value:
+			return 1
+		}
+		return 0
+	}
+	return a.BigValue().Cmp(b.BigValue())
+}
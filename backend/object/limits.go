@@ -0,0 +1,64 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Messages that start the error of a run stopped through Limits
+const (
+	Cancelled      = "execution cancelled"
+	BudgetExceeded = "budget exceeded"
+)
+
+// CheckInterval is how many steps an engine runs between calls to
+// Limits.Monitor
+const CheckInterval = 1024
+
+// Limits let an embedder stop a run that takes too long or recurses too
+// deeply. The zero value imposes nothing beyond the engine's own stack
+// limit.
+type Limits struct {
+	// MaxDepth caps the number of nested Monkey calls, modules included
+	MaxDepth int
+
+	// Monitor is called every CheckInterval steps (VM instructions or
+	// evaluated nodes) with the steps run so far. A non-nil error stops
+	// the run with that error. It may block, e.g. to yield to an event
+	// loop. The steps of every task count; as tasks run in parallel
+	// outside WASM, Monitor may be called from several goroutines at once.
+	Monitor func(steps int) error
+}
+
+// DepthExceeded returns the error to stop with when starting another call,
+// with depth calls already active, would go over MaxDepth
+func (l Limits) DepthExceeded(depth int) error {
+	if l.MaxDepth > 0 && depth >= l.MaxDepth {
+		return fmt.Errorf("%s: more than %d nested calls", BudgetExceeded, l.MaxDepth)
+	}
+	return nil
+}
+
+// Budget returns Limits whose Monitor stops a run after maxSteps steps or
+// once it has run for longer than timeout, and cancels it when ctx is
+// done. A zero maxSteps or timeout leaves that bound off.
+func Budget(ctx context.Context, maxSteps int, timeout time.Duration) Limits {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	return Limits{Monitor: func(steps int) error {
+		if ctx.Err() != nil {
+			return errors.New(Cancelled)
+		}
+		if maxSteps > 0 && steps > maxSteps {
+			return fmt.Errorf("%s: more than %d steps", BudgetExceeded, maxSteps)
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			return fmt.Errorf("%s: ran for more than %v", BudgetExceeded, timeout)
+		}
+		return nil
+	}}
+}
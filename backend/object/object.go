@@ -0,0 +1,254 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"strings"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/code"
+)
+
+// runtime object system kept concise
+
+type ObjectType string
+
+const (
+	NULL_OBJ  = "NULL"
+	ERROR_OBJ = "ERROR"
+
+	INTEGER_OBJ = "INTEGER"
+	BOOLEAN_OBJ = "BOOLEAN"
+	STRING_OBJ  = "STRING"
+
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+
+	FUNCTION_OBJ = "FUNCTION"
+	BUILTIN_OBJ  = "BUILTIN"
+
+	ARRAY_OBJ = "ARRAY"
+	HASH_OBJ  = "HASH"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
+	CLOSURE_OBJ           = "CLOSURE"
+
+	QUOTE_OBJ = "QUOTE"
+	MACRO_OBJ = "MACRO"
+
+	CHANNEL_OBJ = "CHANNEL"
+)
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+type Hashable interface { HashKey() HashKey }
+
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+// Integer is an int64, or a big integer when Big is set (see integer.go)
+type Integer struct { Value int64; Big *big.Int }
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { if i.Big != nil { return i.Big.String() }; return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) HashKey() HashKey {
+	if i.Big != nil { h := fnv.New64a(); h.Write(i.Big.Bytes()); return HashKey{Type: i.Type(), Value: h.Sum64() ^ uint64(i.Big.Sign())} }
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+type Boolean struct { Value bool }
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) HashKey() HashKey { var v uint64; if b.Value { v = 1 } else { v = 0 }; return HashKey{Type: b.Type(), Value: v} }
+
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+type ReturnValue struct { Value Object }
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+// Error is a runtime error. Line and Column locate the failing node (0 when
+// unknown) and Stack lists the active Monkey calls, innermost first.
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+	Stack   []StackFrame
+	Payload Object // the value passed to error(), if it raised this error
+	Fatal   bool   // ends the run even inside try; see Scheduler
+	Cause   error  // the Go error behind this one, e.g. a failed import
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+// Error lets the VM return runtime errors as Go errors
+func (e *Error) Error() string { return e.Message }
+
+// Unwrap returns the cause of the error, if it has one
+func (e *Error) Unwrap() error { return e.Cause }
+
+// StackFrame is one Monkey call in an error's stack trace: the function's
+// name, or the binding it was called through, and the call site. Hidden
+// frames are calls of library functions; see HideFrames.
+type StackFrame struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Hidden   bool   `json:"hidden,omitempty"`
+}
+
+// MaxStackFrames caps the frames kept in a trace, innermost first, so a
+// stack overflow still produces a readable error
+const MaxStackFrames = 32
+
+type Function struct {
+	Name       string
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+	Hidden     bool // see ast.FunctionLiteral
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	params := []string{}
+	for _, p := range f.Parameters { params = append(params, p.String()) }
+	return inspectFunction(params, f.Body.String())
+}
+
+// inspectFunction formats evaluator functions and VM closures alike
+func inspectFunction(params []string, body string) string {
+	var out bytes.Buffer
+	out.WriteString("fn")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(body)
+	out.WriteString("\n}")
+	return out.String()
+}
+
+// Quote is unevaluated code, made by quote() and spliced into other code
+// by unquote()
+type Quote struct { Node ast.Node }
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+// Macro is a macro bound with let. Its parameters receive the arguments of
+// a call as quotes, and the quote it returns replaces the call.
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	params := []string{}
+	for _, p := range m.Parameters { params = append(params, p.String()) }
+	return "macro(" + strings.Join(params, ", ") + ") {\n" + m.Body.String() + "\n}"
+}
+
+type String struct { Value string }
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+func (s *String) HashKey() HashKey { h := fnv.New64a(); h.Write([]byte(s.Value)); return HashKey{Type: s.Type(), Value: h.Sum64()} }
+
+type Builtin struct { Fn BuiltinFunction }
+
+// BuiltinFunction receives the engine running the call so builtins such
+// as import can reach engine state; host may be nil outside a run
+type BuiltinFunction func(host Host, args ...Object) Object
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
+
+type Array struct { Elements []Object }
+
+func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+func (ao *Array) Inspect() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, e := range ao.Elements { elements = append(elements, e.Inspect()) }
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type HashPair struct { Key Object; Value Object }
+
+// Hash keeps insertion order: Keys lists each key of Pairs once, in the
+// order it was first set. Build hashes with Set so the two stay in step.
+type Hash struct { Pairs map[HashKey]HashPair; Keys []HashKey }
+
+func NewHash() *Hash { return &Hash{Pairs: make(map[HashKey]HashPair)} }
+
+// Set stores pair under key; a key already present keeps its position
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if h.Pairs == nil { h.Pairs = make(map[HashKey]HashPair) }
+	if _, ok := h.Pairs[key]; !ok { h.Keys = append(h.Keys, key) }
+	h.Pairs[key] = pair
+}
+
+// Ordered lists the pairs in insertion order
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, len(h.Keys))
+	for i, key := range h.Keys { pairs[i] = h.Pairs[key] }
+	return pairs
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range h.Ordered() { pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect())) }
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// compiled function + closure for VM
+
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+
+	// Name, Parameters and Body describe the source literal for display;
+	// SourceMap locates instructions for error positions and traces
+	Name       string
+	Parameters []string
+	Body       string
+	SourceMap  code.SourceMap
+	Hidden     bool // see ast.FunctionLiteral
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string  { return inspectFunction(cf.Parameters, cf.Body) }
+
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+// Closures report FUNCTION so type errors read the same in both engines
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string  { return inspectFunction(c.Fn.Parameters, c.Fn.Body) }
+
+
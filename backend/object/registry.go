@@ -0,0 +1,212 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"monkey-playground-backend/token"
+)
+
+// Param describes one parameter of a builtin
+type Param struct {
+	Name string
+	// Types lists the argument types accepted, any value when empty.
+	// FUNCTION also accepts builtins.
+	Types []ObjectType
+	// Optional parameters may be left off the end of a call
+	Optional bool
+	// A Variadic parameter comes last and takes any number of arguments
+	Variadic bool
+}
+
+// BuiltinDefinition declares a builtin function: its name, parameters
+// and documentation. The arguments of every call are checked against
+// Params before Fn runs, so Fn only handles checks the metadata cannot
+// express.
+type BuiltinDefinition struct {
+	Name        string
+	Params      []Param
+	Description string
+	// Examples are short programs, each followed by a comment giving its
+	// result, as in `len("abc") // 3`
+	Examples []string
+	Fn       BuiltinFunction
+
+	// Builtin is the function object programs call, set up by
+	// RegisterBuiltin
+	Builtin *Builtin
+}
+
+// MaxBuiltins is how many builtins there can be in all, as OpGetBuiltin
+// takes a one-byte index
+const MaxBuiltins = 256
+
+// RegisterBuiltin adds a builtin for programs in both engines. Embedders
+// call it before running programs: sessions created earlier do not see
+// it, and registering is not safe while programs run. Tasks running in
+// parallel may call def.Fn at the same time.
+func RegisterBuiltin(def BuiltinDefinition) error {
+	if len(Builtins) >= MaxBuiltins {
+		return fmt.Errorf("builtin %q: cannot register more than %d builtins", def.Name, MaxBuiltins)
+	}
+	if def.Name == "" || token.LookupIdent(def.Name) != token.IDENT || !isIdentifier(def.Name) {
+		return fmt.Errorf("builtin name %q is not an identifier", def.Name)
+	}
+	if GetBuiltinByName(def.Name) != nil {
+		return fmt.Errorf("builtin %q is already defined", def.Name)
+	}
+	if def.Fn == nil {
+		return fmt.Errorf("builtin %q has no function", def.Name)
+	}
+	optional := false
+	for i, p := range def.Params {
+		switch {
+		case p.Variadic && i != len(def.Params)-1:
+			return fmt.Errorf("builtin %q: variadic parameter %q must come last", def.Name, p.Name)
+		case optional && !p.Optional && !p.Variadic:
+			return fmt.Errorf("builtin %q: parameter %q follows an optional one", def.Name, p.Name)
+		}
+		optional = optional || p.Optional
+	}
+	Builtins = append(Builtins, define(def))
+	return nil
+}
+
+// define sets up def's function object; the builtins table uses it
+// directly, as its entries are known to be valid
+func define(def BuiltinDefinition) *BuiltinDefinition {
+	d := &def
+	d.Builtin = &Builtin{Fn: func(host Host, args ...Object) Object {
+		if err := d.check(args); err != nil {
+			return err
+		}
+		return d.Fn(host, args...)
+	}}
+	return d
+}
+
+func isIdentifier(name string) bool {
+	for i, ch := range name {
+		letter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+		if !letter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Arity returns the fewest and most arguments def takes; max is -1 when
+// the last parameter is variadic
+func (def *BuiltinDefinition) Arity() (min, max int) {
+	for _, p := range def.Params {
+		switch {
+		case p.Variadic:
+			return min, -1
+		case !p.Optional:
+			min++
+		}
+		max++
+	}
+	return min, max
+}
+
+// Signature shows how def is called, as in "substr(s, start, length?)"
+func (def *BuiltinDefinition) Signature() string {
+	params := make([]string, len(def.Params))
+	for i, p := range def.Params {
+		params[i] = p.Name
+		if p.Optional {
+			params[i] += "?"
+		}
+		if p.Variadic {
+			params[i] += "..."
+		}
+	}
+	return def.Name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// check returns the error for the first argument that does not match
+// def's parameters
+func (def *BuiltinDefinition) check(args []Object) *Error {
+	min, max := def.Arity()
+	switch {
+	case max < 0 && len(args) < min:
+		return newError("wrong number of arguments. got=%d, want at least %d", len(args), min)
+	case max < 0 || min <= len(args) && len(args) <= max:
+	case min == max:
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), min)
+	case max == min+1:
+		return newError("wrong number of arguments. got=%d, want=%d or %d", len(args), min, max)
+	default:
+		return newError("wrong number of arguments. got=%d, want=%d to %d", len(args), min, max)
+	}
+
+	for i, arg := range args {
+		p := def.Params[len(def.Params)-1]
+		if i < len(def.Params) {
+			p = def.Params[i]
+		}
+		if accepts(p, arg) {
+			continue
+		}
+		switch {
+		case p.Variadic || i >= len(ordinals):
+			return newError("argument %d to `%s` must be %s, got %s", i+1, def.Name, p.Types[0], arg.Type())
+		case len(p.Types) > 1:
+			return newError("argument to `%s` not supported, got %s", def.Name, arg.Type())
+		case len(def.Params) == 1:
+			return newError("argument to `%s` must be %s, got %s", def.Name, p.Types[0], arg.Type())
+		default:
+			return newError("%s argument to `%s` must be %s, got %s", ordinals[i], def.Name, p.Types[0], arg.Type())
+		}
+	}
+	return nil
+}
+
+var ordinals = []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth"}
+
+func accepts(p Param, arg Object) bool {
+	if len(p.Types) == 0 {
+		return true
+	}
+	for _, t := range p.Types {
+		if arg.Type() == t || t == FUNCTION_OBJ && arg.Type() == BUILTIN_OBJ {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeBuiltins lists every builtin for editors and documentation,
+// in the same shape for the API and the WASM module. Each entry has
+// "name", "signature", "parameters" ({"name", "types", "optional",
+// "variadic"}), "minArgs", "maxArgs" (-1 when variadic), "description"
+// and "examples".
+func DescribeBuiltins() []any {
+	list := make([]any, len(Builtins))
+	for i, def := range Builtins {
+		params := make([]any, len(def.Params))
+		for j, p := range def.Params {
+			types := make([]any, len(p.Types))
+			for k, t := range p.Types {
+				types[k] = string(t)
+			}
+			params[j] = map[string]any{"name": p.Name, "types": types, "optional": p.Optional, "variadic": p.Variadic}
+		}
+		examples := make([]any, len(def.Examples))
+		for j, e := range def.Examples {
+			examples[j] = e
+		}
+		min, max := def.Arity()
+		list[i] = map[string]any{
+			"name":        def.Name,
+			"signature":   def.Signature(),
+			"parameters":  params,
+			"minArgs":     min,
+			"maxArgs":     max,
+			"description": def.Description,
+			"examples":    examples,
+		}
+	}
+	return list
+}
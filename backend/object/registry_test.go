@@ -0,0 +1,25 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRegisterBuiltinLimit(t *testing.T) {
+	saved := Builtins
+	defer func() { Builtins = saved }()
+
+	fn := GetBuiltinByName("len").Fn
+	for len(Builtins) < MaxBuiltins {
+		if err := RegisterBuiltin(BuiltinDefinition{Name: fmt.Sprintf("test_%d", len(Builtins)), Fn: fn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := RegisterBuiltin(BuiltinDefinition{Name: "test_overflow", Fn: fn})
+	if err == nil {
+		t.Fatalf("registered builtin %d; OpGetBuiltin cannot address it", MaxBuiltins)
+	}
+	if GetBuiltinByName("test_overflow") != nil {
+		t.Error("the rejected builtin was added")
+	}
+}
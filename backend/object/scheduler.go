@@ -0,0 +1,151 @@
+//go:build !(js && wasm)
+
+package object
+
+import "sync"
+
+// Scheduler runs the tasks of one run in parallel. mu guards it and the
+// channels its tasks use; a task blocked on a channel or waiting for its
+// turn in Exclusive sleeps on its wake channel until another task, or the
+// failure of the run, wakes it.
+type Scheduler struct {
+	mu      sync.Mutex
+	main    *Task
+	tasks   []*Task // alive, main first
+	running int     // alive tasks that are not blocked
+	joining bool    // main has finished and waits for the others
+	failed  *Error
+	steps   int
+
+	owner *Task   // the task inside Exclusive
+	queue []*Task // tasks waiting to enter it
+}
+
+// NewScheduler returns the main task of a new scheduler, for a run whose
+// main program has taken steps so far
+func NewScheduler(steps int) *Task {
+	s := &Scheduler{running: 1, steps: steps}
+	s.main = newTask(s)
+	s.tasks = []*Task{s.main}
+	return s.main
+}
+
+// Spawn starts a task that calls run on a goroutine of its own. run's
+// result is discarded unless it is an *Error, which fails the whole run.
+func (t *Task) Spawn(run func(*Task) Object) *Error {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed != nil {
+		return s.stopped(t)
+	}
+	if len(s.tasks) >= maxTasks {
+		return newError("too many tasks: at most %d can be alive at once", maxTasks)
+	}
+	task := newTask(s)
+	s.tasks = append(s.tasks, task)
+	s.running++
+	go func() { s.exit(task, runTask(task, run)) }()
+	return nil
+}
+
+// Finish ends the main program's part of the run, which failed if err is
+// set. It waits for the other tasks to finish, or stops them after a
+// failure, and returns the run's error: err, the first error of a task or
+// a deadlock.
+func (t *Task) Finish(err *Error) *Error {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.fail(err)
+	}
+	s.joining = true
+	for len(s.tasks) > 1 {
+		s.block(t)
+	}
+	return s.failed
+}
+
+// Check is called by a running task every CheckInterval steps. It returns
+// the steps all tasks of the run have taken, and once the run has failed,
+// the error t stops with.
+func (t *Task) Check() (int, *Error) {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps += CheckInterval
+	if s.failed != nil {
+		return s.steps, s.stopped(t)
+	}
+	return s.steps, nil
+}
+
+func (s *Scheduler) lock()   { s.mu.Lock() }
+func (s *Scheduler) unlock() { s.mu.Unlock() }
+
+// park blocks t, the running task, until another task unblocks it
+func (s *Scheduler) park(t *Task) *Error {
+	if s.failed != nil {
+		return s.stopped(t)
+	}
+	return s.block(t)
+}
+
+// block sleeps until t is woken, releasing mu meanwhile. When t was the
+// last task running, the run has deadlocked.
+func (s *Scheduler) block(t *Task) *Error {
+	t.parked = true
+	s.running--
+	if s.running == 0 && s.failed == nil {
+		s.fail(&Error{Message: Deadlock, Fatal: true})
+	}
+	s.mu.Unlock()
+	err := <-t.wake
+	s.mu.Lock()
+	return err
+}
+
+// unblock lets t continue, unless the failure of the run already woke it
+func (s *Scheduler) unblock(t *Task) {
+	if !t.parked {
+		return
+	}
+	t.parked = false
+	s.running++
+	t.wake <- nil
+}
+
+// fail records the run's error and wakes every blocked task to stop; the
+// running ones stop at their next Check
+func (s *Scheduler) fail(err *Error) {
+	if s.failed != nil {
+		return
+	}
+	s.failed = err
+	for _, t := range s.tasks {
+		if t.parked {
+			t.parked = false
+			s.running++
+			t.wake <- s.stopped(t)
+		}
+	}
+}
+
+// exit removes t, which has finished with err if it failed
+func (s *Scheduler) exit(t *Task, err *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = withoutTask(s.tasks, t)
+	s.running--
+	if err != nil && s.failed == nil {
+		err.Fatal = true
+		s.fail(err)
+	}
+	switch {
+	case s.joining && len(s.tasks) == 1:
+		s.unblock(s.main)
+	case s.running == 0 && s.failed == nil:
+		s.fail(&Error{Message: Deadlock, Fatal: true})
+	}
+}
@@ -0,0 +1,130 @@
+//go:build js && wasm
+
+package object
+
+// Scheduler runs the tasks of one run in turns. A task keeps running until
+// it finishes or blocks, and then the task that has been runnable longest
+// continues, so runs are deterministic. Only the running task uses the
+// scheduler, so it needs no locking: the goroutines hand over through
+// their wake channels.
+type Scheduler struct {
+	main    *Task
+	tasks   []*Task // alive, main first
+	ready   []*Task // runnable, in the order they became runnable
+	joining bool    // main has finished and waits for the others
+	failed  *Error
+	steps   int
+
+	owner *Task   // the task inside Exclusive
+	queue []*Task // tasks waiting to enter it
+}
+
+// NewScheduler returns the main task of a new scheduler, for a run whose
+// main program has taken steps so far
+func NewScheduler(steps int) *Task {
+	s := &Scheduler{steps: steps}
+	s.main = newTask(s)
+	s.tasks = []*Task{s.main}
+	return s.main
+}
+
+// Spawn adds a task that calls run when its turn comes. run's result is
+// discarded unless it is an *Error, which fails the whole run.
+func (t *Task) Spawn(run func(*Task) Object) *Error {
+	s := t.s
+	if len(s.tasks) >= maxTasks {
+		return newError("too many tasks: at most %d can be alive at once", maxTasks)
+	}
+	task := newTask(s)
+	s.tasks = append(s.tasks, task)
+	s.ready = append(s.ready, task)
+	go func() {
+		if err := <-task.wake; err != nil {
+			s.exit(task, nil)
+			return
+		}
+		s.exit(task, runTask(task, run))
+	}()
+	return nil
+}
+
+// Finish ends the main program's part of the run, which failed if err is
+// set. It waits for the other tasks to finish, or stops them after a
+// failure, and returns the run's error: err, the first error of a task or
+// a deadlock.
+func (t *Task) Finish(err *Error) *Error {
+	s := t.s
+	if len(s.tasks) == 1 {
+		return err
+	}
+	if err != nil {
+		s.failed = err
+	}
+	s.joining = true
+	return s.park(t)
+}
+
+// Check is called by a running task every CheckInterval steps and returns
+// the steps all tasks of the run have taken. Tasks only stop where they
+// block, so it never fails.
+func (t *Task) Check() (int, *Error) {
+	t.s.steps += CheckInterval
+	return t.s.steps, nil
+}
+
+func (s *Scheduler) lock()   {}
+func (s *Scheduler) unlock() {}
+
+// park blocks t, the running task, until another task unblocks it
+func (s *Scheduler) park(t *Task) *Error {
+	t.parked = true
+	s.next()
+	err := <-t.wake
+	t.parked = false
+	return err
+}
+
+// unblock makes t runnable again
+func (s *Scheduler) unblock(t *Task) {
+	if !t.parked {
+		return
+	}
+	t.parked = false
+	s.ready = append(s.ready, t)
+}
+
+// exit removes t, which has finished with err if it failed
+func (s *Scheduler) exit(t *Task, err *Error) {
+	s.tasks = withoutTask(s.tasks, t)
+	if err != nil && s.failed == nil {
+		err.Fatal = true
+		s.failed = err
+	}
+	if s.failed == nil && s.joining && len(s.tasks) == 1 {
+		s.unblock(s.main)
+	}
+	s.next()
+}
+
+// next hands the turn to the next runnable task. Once the run has failed,
+// it stops the other tasks one at a time and then wakes the main program
+// with the failure.
+func (s *Scheduler) next() {
+	if s.failed == nil && len(s.ready) == 0 {
+		s.failed = &Error{Message: Deadlock, Fatal: true}
+	}
+	if s.failed == nil {
+		t := s.ready[0]
+		s.ready = s.ready[1:]
+		t.wake <- nil
+		return
+	}
+	s.ready = nil
+	for _, t := range s.tasks {
+		if t != s.main {
+			t.wake <- s.stopped(t)
+			return
+		}
+	}
+	s.main.wake <- s.failed
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStringResult bounds strings built by repeat so a program cannot
+// exhaust memory with a single call
+const maxStringResult = 1 << 24
+
+// String builtins. Indexes and lengths count bytes, like len; chars and
+// split with "" break strings at UTF-8 characters.
+
+func builtinSplit(host Host, args ...Object) Object {
+	parts := strings.Split(args[0].(*String).Value, args[1].(*String).Value)
+	return stringArray(parts)
+}
+
+func builtinJoin(host Host, args ...Object) Object {
+	elements := args[0].(*Array).Elements
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		parts[i] = e.Inspect()
+	}
+	return &String{Value: strings.Join(parts, args[1].(*String).Value)}
+}
+
+func builtinSubstr(host Host, args ...Object) Object {
+	s := args[0].(*String).Value
+	start := args[1].(*Integer).Value
+	// A negative start counts from the end; both ends are clamped
+	if start < 0 {
+		start += int64(len(s))
+	}
+	start = min(max(start, 0), int64(len(s)))
+	end := int64(len(s))
+	if len(args) == 3 {
+		length := args[2].(*Integer).Value
+		if length < 0 {
+			return newError("length given to `substr` must not be negative, got %d", length)
+		}
+		end = min(start+length, end)
+	}
+	return &String{Value: s[start:end]}
+}
+
+func builtinIndexOf(host Host, args ...Object) Object {
+	switch haystack := args[0].(type) {
+	case *String:
+		needle, ok := args[1].(*String)
+		if !ok {
+			return newError("second argument to `index_of` must be STRING, got %s", args[1].Type())
+		}
+		return &Integer{Value: int64(strings.Index(haystack.Value, needle.Value))}
+	default:
+		for i, e := range haystack.(*Array).Elements {
+			if Equal(e, args[1]) {
+				return &Integer{Value: int64(i)}
+			}
+		}
+		return &Integer{Value: -1}
+	}
+}
+
+func builtinContains(host Host, args ...Object) Object {
+	index := builtinIndexOf(host, args...)
+	if err, ok := index.(*Error); ok {
+		return &Error{Message: strings.Replace(err.Message, "`index_of`", "`contains`", 1)}
+	}
+	return &Boolean{Value: index.(*Integer).Value >= 0}
+}
+
+// stringFunction makes a builtin applying f to its single STRING argument
+func stringFunction(f func(string) string) BuiltinFunction {
+	return func(host Host, args ...Object) Object {
+		return &String{Value: f(args[0].(*String).Value)}
+	}
+}
+
+func builtinReplace(host Host, args ...Object) Object {
+	s, old, replacement := args[0].(*String).Value, args[1].(*String).Value, args[2].(*String).Value
+	return &String{Value: strings.ReplaceAll(s, old, replacement)}
+}
+
+func builtinChars(host Host, args ...Object) Object {
+	s := args[0].(*String).Value
+	chars := make([]string, 0, utf8.RuneCountInString(s))
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		chars = append(chars, s[:size])
+		s = s[size:]
+	}
+	return stringArray(chars)
+}
+
+func builtinRepeat(host Host, args ...Object) Object {
+	s, count := args[0].(*String).Value, args[1].(*Integer).Value
+	if count < 0 {
+		return newError("count given to `repeat` must not be negative, got %d", count)
+	}
+	if count > 0 && int64(len(s)) > maxStringResult/count {
+		return newError("result of `repeat` is too long (limit %d bytes)", maxStringResult)
+	}
+	return &String{Value: strings.Repeat(s, int(count))}
+}
+
+func builtinToString(host Host, args ...Object) Object {
+	if s, ok := args[0].(*String); ok {
+		return s
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+func builtinToInt(host Host, args ...Object) Object {
+	s, ok := args[0].(*String)
+	if !ok {
+		return args[0]
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s.Value), 10)
+	if !ok {
+		return newError("could not convert %q to INTEGER", s.Value)
+	}
+	return NewBigInteger(n)
+}
+
+func stringArray(values []string) *Array {
+	elements := make([]Object, len(values))
+	for i, v := range values {
+		elements[i] = &String{Value: v}
+	}
+	return &Array{Elements: elements}
+}
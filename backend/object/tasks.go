@@ -0,0 +1,272 @@
+package object
+
+import "fmt"
+
+// Tasks and channels. spawn(f) runs f as a task of the current run, and
+// tasks talk through channels with send, recv and close. Each task runs on
+// a goroutine of its own. In the server and the CLI the tasks of a run
+// run in parallel; in WASM they take turns, so browser runs are
+// deterministic. Either way a task blocked on a channel waits on a Go
+// channel of its own until another task readies it. A run ends once its
+// main program and every task have finished; when all of them are blocked
+// instead, the run fails with Deadlock.
+
+// Deadlock is the error of a run whose tasks are all blocked
+const Deadlock = "deadlock: all tasks are blocked"
+
+// taskStopped is the error that ends the other tasks of a failed run
+const taskStopped = "task stopped"
+
+// maxTasks bounds the tasks alive at once in a run
+const maxTasks = 1024
+
+// maxChannelCapacity bounds the buffer chan allocates
+const maxChannelCapacity = 1 << 16
+
+// Task is the main program or a spawned function. Each task runs on a
+// host of its own (an evaluator host or VM), which passes the task to the
+// channel operations it makes.
+type Task struct {
+	s      *Scheduler
+	wake   chan *Error
+	parked bool
+}
+
+func newTask(s *Scheduler) *Task {
+	return &Task{s: s, wake: make(chan *Error, 1)}
+}
+
+// runTask calls run for t and returns its error, turning a panic into one
+// so that it fails the run instead of the process
+func runTask(t *Task, run func(*Task) Object) (err *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &Error{Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+	err, _ = run(t).(*Error)
+	return err
+}
+
+// stopped is the error a task of a failed run stops with: the run's error
+// for the main program, which reports it
+func (s *Scheduler) stopped(t *Task) *Error {
+	if t == s.main {
+		return s.failed
+	}
+	return &Error{Message: taskStopped, Fatal: true}
+}
+
+// Exclusive runs f while no other task of the run is inside Exclusive, as
+// import does to load modules one at a time. A task arriving meanwhile
+// waits its turn, blocked as in recv; calls nested in f run directly.
+func (t *Task) Exclusive(f func()) *Error {
+	s := t.s
+	s.lock()
+	switch {
+	case s.owner == t:
+		s.unlock()
+		f()
+		return nil
+	case s.owner == nil:
+		s.owner = t
+	default:
+		s.queue = append(s.queue, t)
+		if err := s.park(t); err != nil {
+			s.queue = withoutTask(s.queue, t)
+			s.unlock()
+			return err
+		}
+	}
+	s.unlock()
+
+	defer func() {
+		s.lock()
+		s.owner = nil
+		if len(s.queue) > 0 {
+			s.owner = s.queue[0]
+			s.queue = s.queue[1:]
+			s.unblock(s.owner)
+		}
+		s.unlock()
+	}()
+	f()
+	return nil
+}
+
+// Channel is a queue of values between tasks. Sends block while Capacity
+// values are waiting, and receives block while none are.
+type Channel struct {
+	Capacity  int
+	buffer    []Object
+	closed    bool
+	senders   []*waiter
+	receivers []*waiter
+}
+
+// waiter is a task blocked on a channel; done is set once a blocked
+// send's value has been taken
+type waiter struct {
+	task  *Task
+	value Object
+	done  bool
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return fmt.Sprintf("chan(%d)", c.Capacity) }
+
+// Send delivers value to a waiting receiver, buffers it, or blocks t
+// until a receiver takes it
+func (t *Task) Send(c *Channel, value Object) *Error {
+	s := t.s
+	s.lock()
+	defer s.unlock()
+	if c.closed {
+		return newError("send on closed channel")
+	}
+	if len(c.receivers) > 0 {
+		r := c.receivers[0]
+		c.receivers = c.receivers[1:]
+		r.value = value
+		s.unblock(r.task)
+		return nil
+	}
+	if len(c.buffer) < c.Capacity {
+		c.buffer = append(c.buffer, value)
+		return nil
+	}
+	w := &waiter{task: t, value: value}
+	c.senders = append(c.senders, w)
+	if err := s.park(t); err != nil {
+		c.senders = without(c.senders, w)
+		return err
+	}
+	if !w.done {
+		return newError("send on closed channel")
+	}
+	return nil
+}
+
+// Receive takes the next value from c, blocking t until there is one. A
+// closed, drained channel gives nil.
+func (t *Task) Receive(c *Channel) (Object, *Error) {
+	s := t.s
+	s.lock()
+	defer s.unlock()
+	if len(c.buffer) > 0 {
+		value := c.buffer[0]
+		c.buffer = c.buffer[1:]
+		if len(c.senders) > 0 {
+			c.buffer = append(c.buffer, s.takeSender(c).value)
+		}
+		return value, nil
+	}
+	if len(c.senders) > 0 {
+		return s.takeSender(c).value, nil
+	}
+	if c.closed {
+		return nil, nil
+	}
+	w := &waiter{task: t}
+	c.receivers = append(c.receivers, w)
+	if err := s.park(t); err != nil {
+		c.receivers = without(c.receivers, w)
+		return nil, err
+	}
+	return w.value, nil
+}
+
+func (s *Scheduler) takeSender(c *Channel) *waiter {
+	w := c.senders[0]
+	c.senders = c.senders[1:]
+	w.done = true
+	s.unblock(w.task)
+	return w
+}
+
+// Close marks c closed: blocked receivers get nil, and blocked and later
+// sends fail
+func (t *Task) Close(c *Channel) *Error {
+	s := t.s
+	s.lock()
+	defer s.unlock()
+	if c.closed {
+		return newError("close of closed channel")
+	}
+	c.closed = true
+	for _, w := range append(c.receivers, c.senders...) {
+		s.unblock(w.task)
+	}
+	c.receivers, c.senders = nil, nil
+	return nil
+}
+
+func without(waiters []*waiter, w *waiter) []*waiter {
+	for i, other := range waiters {
+		if other == w {
+			return append(waiters[:i:i], waiters[i+1:]...)
+		}
+	}
+	return waiters
+}
+
+func withoutTask(tasks []*Task, t *Task) []*Task {
+	for i, other := range tasks {
+		if other == t {
+			return append(tasks[:i:i], tasks[i+1:]...)
+		}
+	}
+	return tasks
+}
+
+func builtinSpawn(host Host, args ...Object) Object {
+	if host == nil {
+		return newError("tasks are not available outside a program run")
+	}
+	if err := host.Spawn(args[0], args[1:]...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func builtinChan(host Host, args ...Object) Object {
+	capacity := int64(0)
+	if len(args) == 1 {
+		capacity = args[0].(*Integer).Value
+	}
+	if capacity < 0 || capacity > maxChannelCapacity {
+		return newError("capacity given to `chan` must be between 0 and %d, got %s", maxChannelCapacity, args[0].Inspect())
+	}
+	return &Channel{Capacity: int(capacity)}
+}
+
+func builtinSend(host Host, args ...Object) Object {
+	if host == nil {
+		return newError("tasks are not available outside a program run")
+	}
+	if err := host.Task().Send(args[0].(*Channel), args[1]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func builtinRecv(host Host, args ...Object) Object {
+	if host == nil {
+		return newError("tasks are not available outside a program run")
+	}
+	value, err := host.Task().Receive(args[0].(*Channel))
+	if err != nil {
+		return err
+	}
+	return value
+}
+
+func builtinClose(host Host, args ...Object) Object {
+	if host == nil {
+		return newError("tasks are not available outside a program run")
+	}
+	if err := host.Task().Close(args[0].(*Channel)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package object
+
+// HideFrames returns err as it looks from the code calling library
+// functions: without their frames, and located at the call into the
+// library when it happened inside one. Functions the library calls back,
+// such as a callback passed to it, are located at that call too, as if a
+// builtin had called them. err itself is left unchanged.
+func HideFrames(err *Error) *Error {
+	hidden := false
+	for _, frame := range err.Stack {
+		hidden = hidden || frame.Hidden
+	}
+	if !hidden {
+		return err
+	}
+
+	visible := *err
+	visible.Stack = nil
+	// entry is the call that entered the library, outermost first; it is
+	// unknown when the trace was cut short inside the library
+	var entry *StackFrame
+	for i := len(err.Stack) - 1; i >= 0; i-- {
+		frame := err.Stack[i]
+		if i+1 < len(err.Stack) && err.Stack[i+1].Hidden && entry != nil {
+			frame.Line, frame.Column = entry.Line, entry.Column
+		}
+		if frame.Hidden {
+			if entry == nil || !err.Stack[i+1].Hidden {
+				entry = &frame
+			}
+			continue
+		}
+		visible.Stack = append([]StackFrame{frame}, visible.Stack...)
+	}
+	if err.Stack[0].Hidden && entry != nil {
+		visible.Line, visible.Column = entry.Line, entry.Column
+	}
+	return &visible
+}
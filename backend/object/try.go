@@ -0,0 +1,70 @@
+package object
+
+import "strings"
+
+// Raising and catching errors. error(x) fails like any runtime error, and
+// try(fn, handler) calls handler with a hash describing the error when fn
+// fails. Runs stopped through Limits cannot be caught, so a cancelled or
+// over-budget program still stops, and neither can deadlocks or the
+// failures of other tasks.
+
+func builtinError(host Host, args ...Object) Object {
+	err := &Error{Message: args[0].Inspect(), Payload: args[0]}
+	if s, ok := args[0].(*String); ok {
+		err.Message = s.Value
+	}
+	return err
+}
+
+func builtinTry(host Host, args ...Object) Object {
+	result := apply(host, args[0])
+	err, ok := result.(*Error)
+	if !ok || !Catchable(err) {
+		return result
+	}
+	return apply(host, args[1], ErrorValue(err))
+}
+
+// Catchable reports whether try may catch err: every error is, except
+// fatal ones and those that stop a run through Limits
+func Catchable(err *Error) bool {
+	return !err.Fatal && !strings.HasPrefix(err.Message, Cancelled) && !strings.HasPrefix(err.Message, BudgetExceeded)
+}
+
+// ErrorValue describes err as the hash try passes to its handler: its
+// "message", the "payload" given to error() (null for other errors), the
+// "line" and "column" it happened at and the "stack" of calls active
+// then, innermost first, as hashes of "function", "line" and "column".
+// Calls of library functions are left out, as HideFrames does.
+func ErrorValue(err *Error) *Hash {
+	err = HideFrames(err)
+	stack := make([]Object, len(err.Stack))
+	for i, frame := range err.Stack {
+		stack[i] = stringKeyHash(
+			"function", &String{Value: frame.Function},
+			"line", &Integer{Value: int64(frame.Line)},
+			"column", &Integer{Value: int64(frame.Column)},
+		)
+	}
+	var payload Object = &Null{}
+	if err.Payload != nil {
+		payload = err.Payload
+	}
+	return stringKeyHash(
+		"message", &String{Value: err.Message},
+		"payload", payload,
+		"line", &Integer{Value: int64(err.Line)},
+		"column", &Integer{Value: int64(err.Column)},
+		"stack", &Array{Elements: stack},
+	)
+}
+
+// stringKeyHash builds a hash from alternating keys and values
+func stringKeyHash(pairs ...any) *Hash {
+	hash := NewHash()
+	for i := 0; i < len(pairs); i += 2 {
+		key := &String{Value: pairs[i].(string)}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: pairs[i+1].(Object)})
+	}
+	return hash
+}
@@ -0,0 +1,158 @@
+// Package prelude holds library modules written in Monkey that a run can
+// load before its own code: core (functional helpers), math and strings.
+// Each selection of modules is parsed, compiled and run once, then shared
+// by every run that loads it. The functions it defines are hidden from
+// error traces; see object.HideFrames.
+package prelude
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/vm"
+)
+
+//go:embed *.monkey
+var sources embed.FS
+
+// Modules lists the prelude modules in the order they load
+var Modules = []string{"core", "math", "strings"}
+
+// Prelude is a loaded selection of modules, ready to enclose in an
+// evaluator environment or to define in the VM's symbol table and globals
+type Prelude struct {
+	// Names are the modules loaded, in the order of Modules
+	Names []string
+
+	// env holds the bindings the evaluator made running the modules. It
+	// is frozen once loaded: runs enclose it and never write to it.
+	env       *object.Environment
+	symbols   *compiler.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+}
+
+var (
+	loadedMu sync.Mutex
+	loaded   = make(map[string]*Prelude)
+)
+
+// Load returns the modules named, loading them on first use. No names give
+// an empty prelude, which defines only the builtins.
+func Load(names []string) (*Prelude, error) {
+	for _, name := range names {
+		if !slices.Contains(Modules, name) {
+			return nil, fmt.Errorf("unknown prelude module %q (want %s)", name, strings.Join(Modules, ", "))
+		}
+	}
+	var selected []string
+	for _, module := range Modules {
+		if slices.Contains(names, module) {
+			selected = append(selected, module)
+		}
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	key := strings.Join(selected, ",")
+	if p, ok := loaded[key]; ok {
+		return p, nil
+	}
+	p, err := load(selected)
+	if err != nil {
+		return nil, err
+	}
+	loaded[key] = p
+	return p, nil
+}
+
+// load compiles the modules into one symbol table and constant pool and
+// runs them, keeping the globals they define, and evaluates them into env
+func load(names []string) (*Prelude, error) {
+	p := &Prelude{Names: names, env: evaluator.NewEnvironment(nil, nil), symbols: compiler.NewSymbolTable(), constants: []object.Object{}}
+	for i, b := range object.Builtins {
+		p.symbols.DefineBuiltin(i, b.Name)
+	}
+	globals := make([]object.Object, vm.GlobalsSize)
+	for _, name := range names {
+		source, err := sources.ReadFile(name + ".monkey")
+		if err != nil {
+			return nil, err
+		}
+		parse := parser.New(lexer.New(string(source)))
+		program := parse.ParseProgram()
+		if len(parse.Errors()) > 0 {
+			return nil, fmt.Errorf("prelude %s: %s", name, parse.Errors()[0])
+		}
+		program = hide(program)
+
+		comp := compiler.NewWithState(p.symbols, p.constants)
+		if err := comp.Compile(program); err != nil {
+			return nil, fmt.Errorf("prelude %s: %w", name, err)
+		}
+		bytecode := comp.Bytecode()
+		p.constants = bytecode.Constants
+		if err := vm.NewWithGlobalsStore(bytecode, globals).Run(); err != nil {
+			return nil, fmt.Errorf("prelude %s: %w", name, err)
+		}
+		if err, ok := evaluator.Eval(program, p.env).(*object.Error); ok {
+			return nil, fmt.Errorf("prelude %s: %w", name, err)
+		}
+	}
+
+	defined := 0
+	for _, sym := range p.symbols.GlobalSymbols() {
+		defined = max(defined, sym.Index+1)
+	}
+	p.globals = globals[:defined:defined]
+	return p, nil
+}
+
+// hide marks every function literal in program as library code
+func hide(program *ast.Program) *ast.Program {
+	return ast.Modify(program, func(node ast.Node) ast.Node {
+		if literal, ok := node.(*ast.FunctionLiteral); ok {
+			literal.Hidden = true
+		}
+		return node
+	}).(*ast.Program)
+}
+
+// Environment returns a top-level environment for one run on the
+// evaluator, as from evaluator.NewEnvironment, enclosing the prelude's
+// bindings
+func (p *Prelude) Environment(loader *modules.Loader, out io.Writer) *object.Environment {
+	return evaluator.NewEnclosedEnvironment(p.env, loader, out)
+}
+
+// SymbolTable returns a new global symbol table defining the builtins and
+// the prelude's bindings
+func (p *Prelude) SymbolTable() *compiler.SymbolTable { return p.symbols.Clone() }
+
+// Constants returns a copy of the prelude's constant pool for a program
+// to add its own constants to
+func (p *Prelude) Constants() []object.Object { return slices.Clip(slices.Clone(p.constants)) }
+
+// Globals returns a new VM globals store holding the prelude's bindings
+func (p *Prelude) Globals() []object.Object {
+	globals := make([]object.Object, vm.GlobalsSize)
+	copy(globals, p.globals)
+	return globals
+}
+
+// Compiler returns a compiler for a program that runs after the prelude,
+// on a VM with Globals
+func (p *Prelude) Compiler() *compiler.Compiler {
+	return compiler.NewWithState(p.SymbolTable(), p.Constants())
+}
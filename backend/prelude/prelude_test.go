@@ -0,0 +1,165 @@
+package prelude_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/prelude"
+	"monkey-playground-backend/session"
+)
+
+// run executes input after the prelude modules named on engine and returns
+// its result, or the error prefixed with "error: " and followed by where it
+// happened
+func run(t *testing.T, engine string, names []string, traces bool, input string) string {
+	t.Helper()
+	s, err := session.New(engine, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadPrelude(names); err != nil {
+		t.Fatal(err)
+	}
+	s.SetPreludeTraces(traces)
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("%q: parse errors: %v", input, p.Errors())
+	}
+	result, err := s.Run(program, io.Discard, object.Limits{})
+	var located *object.Error
+	if errors.As(err, &located) {
+		where := fmt.Sprintf("%d:%d", located.Line, located.Column)
+		for _, frame := range located.Stack {
+			where += fmt.Sprintf(" < %s %d:%d", frame.Function, frame.Line, frame.Column)
+		}
+		return "error: " + located.Message + " at " + where
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return result.Inspect()
+}
+
+func assertBothEngines(t *testing.T, names []string, traces bool, input, want string) {
+	t.Helper()
+	for _, engine := range []string{session.VM, session.Eval} {
+		if got := run(t, engine, names, traces, input); got != want {
+			t.Errorf("%s: %q: got=%q, want=%q", engine, input, got, want)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	all := prelude.Modules
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`identity(5)`, "5"},
+		{`compose(fn(x) { x + 1 }, fn(x) { x * 2 })(5)`, "11"},
+		{`pipe(3, [fn(x) { x + 1 }, fn(x) { x * 10 }])`, "40"},
+		{`each([1, 2], puts)`, "null"},
+		{`find([1, 4, 9], fn(x) { x > 3 })`, "4"},
+		{`find([1], fn(x) { x > 3 })`, "null"},
+		{`[any([1, 2], fn(x) { x > 1 }), any([], fn(x) { true }), all([1, 2], fn(x) { x > 1 }), all([], fn(x) { false })]`, "[true, false, false, true]"},
+		{`count([1, 2, 3], fn(x) { x > 1 })`, "2"},
+		{`flat_map([1, 2], fn(x) { [x, x] })`, "[1, 1, 2, 2]"},
+		{`[abs(-3), abs(3), min(2, 1), max(2, 1), sum([1, 2, 3])]`, "[3, 3, 1, 2, 6]"},
+		{`[pow(2, 10), pow(3, 0), pow(2, 64)]`, "[1024, 1, 18446744073709551616]"},
+		{`[gcd(12, 18), gcd(-4, 6), gcd(0, 5), lcm(4, 6), lcm(0, 3)]`, "[6, 2, 5, 12, 0]"},
+		{`[starts_with("monkey", "mon"), starts_with("mon", "monkey"), ends_with("monkey", "key"), ends_with("ey", "key")]`, "[true, false, true, false]"},
+		{`[pad_left("7", 3, "0"), pad_right("ab", 4, "."), pad_left("long", 2, " ")]`, "[007, ab.., long]"},
+		{`[capitalize("monkey"), capitalize("")]`, "[Monkey, ]"},
+		{`words(" the  quick fox ")`, "[the, quick, fox]"},
+		{`let abs = fn(x) { "mine" }; abs(-1)`, "mine"},
+	}
+
+	for _, tt := range tests {
+		assertBothEngines(t, all, false, tt.input, tt.want)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	assertBothEngines(t, []string{"math"}, false, `abs(-1)`, "1")
+	assertBothEngines(t, []string{"math"}, false, `identity(1)`, "error: undefined variable identity at 1:1")
+	assertBothEngines(t, nil, false, `abs(-1)`, "error: undefined variable abs at 1:1")
+
+	if _, err := prelude.Load([]string{"core", "nope"}); err == nil || err.Error() != `unknown prelude module "nope" (want core, math, strings)` {
+		t.Errorf("unknown module: got %v", err)
+	}
+	first, _ := prelude.Load([]string{"math", "core"})
+	second, _ := prelude.Load([]string{"core", "math", "core"})
+	if first != second {
+		t.Errorf("a selection was loaded twice")
+	}
+}
+
+func TestSharedEnvironment(t *testing.T) {
+	p, err := prelude.Load([]string{"math"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := p.Environment(nil, nil), p.Environment(nil, nil)
+	a, _ := first.Get("abs")
+	b, _ := second.Get("abs")
+	if a == nil || a != b {
+		t.Errorf("runs got abs %v and %v, want the one function evaluated at load", a, b)
+	}
+
+	// A run shadowing a prelude function leaves it for the next run
+	program := parser.New(lexer.New(`let abs = 1; abs`)).ParseProgram()
+	if got := evaluator.Eval(program, first).Inspect(); got != "1" {
+		t.Errorf("shadowed abs got %s, want 1", got)
+	}
+	program = parser.New(lexer.New(`abs(-2)`)).ParseProgram()
+	if got := evaluator.Eval(program, second).Inspect(); got != "2" {
+		t.Errorf("abs(-2) after shadowing got %s, want 2", got)
+	}
+}
+
+func TestHiddenFrames(t *testing.T) {
+	all := prelude.Modules
+	tests := []struct {
+		input  string
+		hidden string // the error as traces report it by default
+		shown  string // with prelude traces
+	}{
+		{
+			"let f = fn() {\n  1 + pow(2, -1)\n};\n1 + f()",
+			"error: pow: negative exponent -1 at 2:7 < f 4:5",
+			"error: pow: negative exponent -1 at 11:18 < pow 2:7 < f 4:5",
+		},
+		{
+			"1 + abs(\"x\")",
+			"error: type mismatch: STRING < INTEGER at 1:5",
+			"error: type mismatch: STRING < INTEGER at 2:25 < abs 1:5",
+		},
+		{
+			"let check = fn(x) { x + true };\neach([1], check)",
+			"error: type mismatch: INTEGER + BOOLEAN at 1:23 < check 2:1",
+			"error: type mismatch: INTEGER + BOOLEAN at 1:23 < check 10:23 < loop 2:1",
+		},
+		{
+			"each([1], fn(x) {\n  1 + abs(true)\n})",
+			"error: type mismatch: BOOLEAN < INTEGER at 2:7 < <anonymous> 1:1",
+			"error: type mismatch: BOOLEAN < INTEGER at 2:25 < abs 2:7 < <anonymous> 10:23 < loop 1:1",
+		},
+		{
+			"try(fn() { 1 + gcd(1, \"x\") }, fn(e) { [e[\"line\"], e[\"column\"], len(e[\"stack\"])] })",
+			"[1, 16, 1]",
+			"[1, 16, 1]",
+		},
+	}
+
+	for _, tt := range tests {
+		assertBothEngines(t, all, false, tt.input, tt.hidden)
+		assertBothEngines(t, all, true, tt.input, tt.shown)
+	}
+}
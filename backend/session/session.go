@@ -0,0 +1,176 @@
+// Package session runs successive programs against shared state, as a
+// REPL does, on either engine. The CLI REPL and the WASM REPL sessions
+// both build on it.
+package session
+
+import (
+	"fmt"
+	"io"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/prelude"
+	"monkey-playground-backend/vm"
+)
+
+// Engine names accepted by New
+const (
+	VM   = "vm"
+	Eval = "eval"
+)
+
+// Session keeps the bindings made by every program it has run
+type Session struct {
+	engine  string
+	loader  *modules.Loader
+	hostFns object.HostFunctions
+
+	// prelude is loaded before the first program; preludeTraces keeps its
+	// calls in error traces
+	prelude       *prelude.Prelude
+	preludeTraces bool
+	ran           bool
+
+	// Evaluator state
+	env *object.Environment
+
+	// VM state. Macros are expanded by the evaluator in macros, which
+	// has no module files, before each program is compiled.
+	symbols   *compiler.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+	macros    *object.Environment
+}
+
+// New returns an empty session on engine ("vm", "eval" or "evaluator");
+// import() resolves through loader, which may be nil
+func New(engine string, loader *modules.Loader) (*Session, error) {
+	s := &Session{loader: loader}
+	switch engine {
+	case VM:
+		s.engine = VM
+		s.symbols = compiler.NewSymbolTable()
+		for i, b := range object.Builtins {
+			s.symbols.DefineBuiltin(i, b.Name)
+		}
+		s.constants = []object.Object{}
+		s.globals = make([]object.Object, vm.GlobalsSize)
+		s.macros = evaluator.NewEnvironment(nil, nil)
+	case Eval, "evaluator":
+		s.engine = Eval
+		s.env = evaluator.NewEnvironment(loader, nil)
+	default:
+		return nil, fmt.Errorf("unknown engine %q (want %s or %s)", engine, VM, Eval)
+	}
+	return s, nil
+}
+
+// Engine returns the name of the session's engine
+func (s *Session) Engine() string { return s.engine }
+
+// Diagnostics returns the import problems reported so far, if the session
+// has a loader
+func (s *Session) Diagnostics() []modules.Diagnostic {
+	if s.loader == nil {
+		return nil
+	}
+	return s.loader.Diagnostics()
+}
+
+// SetHostFunctions grants later runs the embedder functions host() may
+// call
+func (s *Session) SetHostFunctions(fns object.HostFunctions) { s.hostFns = fns }
+
+// LoadPrelude defines the prelude modules named (see package prelude) for
+// every later program. It must come before the first Run.
+func (s *Session) LoadPrelude(names []string) error {
+	if s.ran {
+		return fmt.Errorf("the prelude must be loaded before the first program")
+	}
+	p, err := prelude.Load(names)
+	if err != nil {
+		return err
+	}
+	s.prelude = p
+	if s.engine == Eval {
+		s.env = p.Environment(s.loader, nil)
+		return nil
+	}
+	s.symbols = p.SymbolTable()
+	s.constants = p.Constants()
+	s.globals = p.Globals()
+	return nil
+}
+
+// Prelude returns the names of the prelude modules loaded
+func (s *Session) Prelude() []string {
+	if s.prelude == nil {
+		return nil
+	}
+	return s.prelude.Names
+}
+
+// SetPreludeTraces keeps calls of prelude functions in the stack traces of
+// later runs' errors, which leave them out by default
+func (s *Session) SetPreludeTraces(show bool) { s.preludeTraces = show }
+
+// Run executes program with puts writing to out (os.Stdout when nil) and
+// returns the value of its last expression. Bindings made before a
+// runtime error are kept. A panic in the engine is returned as an error so
+// a crashing program cannot take the REPL down with it.
+func (s *Session) Run(program *ast.Program, out io.Writer, limits object.Limits) (result object.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("internal error: %v", r)
+		}
+		if located, ok := err.(*object.Error); ok && !s.preludeTraces {
+			err = object.HideFrames(located)
+		}
+	}()
+	s.ran = true
+
+	if s.engine == Eval {
+		evaluator.SetOutput(s.env, out)
+		evaluator.SetLimits(s.env, limits)
+		evaluator.SetHostFunctions(s.env, s.hostFns)
+		result = evaluator.Eval(program, s.env)
+		if errObj, ok := result.(*object.Error); ok {
+			return nil, errObj
+		}
+		return result, nil
+	}
+
+	evaluator.SetOutput(s.macros, out)
+	evaluator.SetLimits(s.macros, limits)
+	if err := evaluator.Expand(program, s.macros); err != nil {
+		return nil, err
+	}
+
+	comp := compiler.NewWithState(s.symbols, s.constants)
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+
+	bytecode := comp.Bytecode()
+	s.constants = bytecode.Constants
+
+	machine := vm.NewWithGlobalsStore(bytecode, s.globals)
+	if out != nil {
+		machine.SetOutput(out)
+	}
+	machine.SetLimits(limits)
+	machine.SetHostFunctions(s.hostFns)
+	if s.loader != nil {
+		machine.EnableModules(s.loader, s.symbols)
+	}
+	err = machine.Run()
+	// Imported modules add constants that later input must not overwrite
+	s.constants = machine.Constants()
+	if err != nil {
+		return nil, err
+	}
+	return machine.LastPoppedStackElem(), nil
+}
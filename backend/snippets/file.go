@@ -0,0 +1,219 @@
+package snippets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore keeps each snippet as a JSON file in a directory so snippets
+// survive restarts without an external database. An index of IDs and
+// hashes is rebuilt from the directory when the store is opened.
+type FileStore struct {
+	dir string
+
+	mu     sync.RWMutex
+	index  map[string]*Snippet // metadata only; Code and Result are read from disk
+	byHash map[string]string
+}
+
+// OpenFileStore opens (creating if needed) a FileStore rooted at dir.
+// Snippet files that cannot be read are logged and left out.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{
+		dir:    dir,
+		index:  make(map[string]*Snippet),
+		byHash: make(map[string]string),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		snippet, err := store.read(strings.TrimSuffix(name, ".json"))
+		if err == nil && snippet.ID != strings.TrimSuffix(name, ".json") {
+			err = fmt.Errorf("file holds snippet %q", snippet.ID)
+		}
+		if err != nil {
+			log.Printf("snippets: skipping %s: %v", filepath.Join(dir, name), err)
+			continue
+		}
+		store.indexSnippet(snippet)
+	}
+
+	return store, nil
+}
+
+func (f *FileStore) Get(id string) (*Snippet, error) {
+	f.mu.RLock()
+	_, ok := f.index[id]
+	f.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return f.read(id)
+}
+
+func (f *FileStore) FindByHash(hash string) (*Snippet, error) {
+	f.mu.RLock()
+	id, ok := f.byHash[hash]
+	f.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return f.Get(id)
+}
+
+func (f *FileStore) Put(s *Snippet) error {
+	if !validID(s.ID) {
+		return fmt.Errorf("invalid snippet id %q", s.ID)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Write to a temporary file first so a crash never leaves half a snippet
+	tmp, err := os.CreateTemp(f.dir, s.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path(s.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	f.indexSnippet(s)
+	return nil
+}
+
+func (f *FileStore) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snippet, ok := f.index[id]
+	if !ok {
+		return nil
+	}
+	delete(f.byHash, snippet.Hash)
+	delete(f.index, id)
+
+	if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f *FileStore) DeleteExpired(now time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for id, meta := range f.index {
+		if !expiredAt(meta, now) {
+			continue
+		}
+		delete(f.byHash, meta.Hash)
+		delete(f.index, id)
+		if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FileStore) Recent(limit int) ([]*Snippet, error) {
+	f.mu.RLock()
+	all := make([]*Snippet, 0, len(f.index))
+	for _, meta := range f.index {
+		all = append(all, meta)
+	}
+	f.mu.RUnlock()
+
+	recent := []*Snippet{}
+	for _, meta := range newestFirst(all, limit) {
+		snippet, err := f.read(meta.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		recent = append(recent, snippet)
+	}
+	return recent, nil
+}
+
+func (f *FileStore) read(id string) (*Snippet, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snippet Snippet
+	if err := json.Unmarshal(data, &snippet); err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+// indexSnippet records metadata for s; callers must hold the write lock
+// (or be the only user, as in OpenFileStore)
+func (f *FileStore) indexSnippet(s *Snippet) {
+	f.index[s.ID] = &Snippet{ID: s.ID, Hash: s.Hash, CreatedAt: s.CreatedAt, ExpiresAt: s.ExpiresAt}
+	f.byHash[s.Hash] = s.ID
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
+
+// validID keeps IDs to base62 so they are always safe file names
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, ch := range id {
+		if !('0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package snippets
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps snippets in process memory; they are lost on restart
+type MemoryStore struct {
+	mu     sync.RWMutex
+	byID   map[string]*Snippet
+	byHash map[string]string
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:   make(map[string]*Snippet),
+		byHash: make(map[string]string),
+	}
+}
+
+func (m *MemoryStore) Get(id string) (*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippet, ok := m.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(snippet), nil
+}
+
+func (m *MemoryStore) FindByHash(hash string) (*Snippet, error) {
+	m.mu.RLock()
+	id, ok := m.byHash[hash]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.Get(id)
+}
+
+func (m *MemoryStore) Put(s *Snippet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.byID[s.ID] = clone(s)
+	m.byHash[s.Hash] = s.ID
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if snippet, ok := m.byID[id]; ok {
+		delete(m.byHash, snippet.Hash)
+		delete(m.byID, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpired(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, snippet := range m.byID {
+		if expiredAt(snippet, now) {
+			delete(m.byHash, snippet.Hash)
+			delete(m.byID, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Recent(limit int) ([]*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	all := make([]*Snippet, 0, len(m.byID))
+	for _, snippet := range m.byID {
+		all = append(all, clone(snippet))
+	}
+	return newestFirst(all, limit), nil
+}
+
+// expiredAt reports whether s has an expiry that is before now
+func expiredAt(s *Snippet, now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)
+}
+
+func clone(s *Snippet) *Snippet {
+	c := *s
+	if s.Result != nil {
+		result := *s.Result
+		c.Result = &result
+	}
+	return &c
+}
+
+func newestFirst(all []*Snippet, limit int) []*Snippet {
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID < all[j].ID
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	return all
+}
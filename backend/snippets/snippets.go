@@ -0,0 +1,255 @@
+// Package snippets stores shareable playground programs under short IDs.
+package snippets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits applied by Service unless overridden
+const (
+	DefaultMaxCodeSize   = 64 * 1024
+	DefaultMaxOutputSize = 64 * 1024
+	DefaultTTL           = 30 * 24 * time.Hour
+	DefaultRecentLimit   = 20
+	MaxRecentLimit       = 100
+
+	minIDLength = 8
+
+	// sweepInterval is how often writes delete expired snippets
+	sweepInterval = time.Hour
+)
+
+var (
+	ErrNotFound     = errors.New("snippet not found")
+	ErrEmptyCode    = errors.New("snippet code is empty")
+	ErrCodeTooLarge = errors.New("snippet code is too large")
+	ErrBadEngine    = errors.New("unknown snippet engine")
+)
+
+// Engines are the engine names a snippet may be saved with. A snippet
+// without an engine runs on the reader's default one.
+var Engines = []string{"vm", "eval"}
+
+// Result is the last execution result saved alongside a snippet, using
+// the same shape as ExecuteResponse
+type Result struct {
+	Result string `json:"result"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Snippet is a saved program
+type Snippet struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`
+	Code      string    `json:"code"`
+	Engine    string    `json:"engine,omitempty"`
+	Result    *Result   `json:"result,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Store persists snippets. Implementations must be safe for concurrent use;
+// expiry and deduplication are handled by Service.
+type Store interface {
+	Get(id string) (*Snippet, error)
+	FindByHash(hash string) (*Snippet, error)
+	Put(s *Snippet) error
+	Delete(id string) error
+	// Recent returns up to limit snippets, newest first
+	Recent(limit int) ([]*Snippet, error)
+	// DeleteExpired removes every snippet that expired before now
+	DeleteExpired(now time.Time) error
+}
+
+// Service applies size limits, expiry and content-hash deduplication on
+// top of a Store
+type Service struct {
+	Store         Store
+	MaxCodeSize   int
+	MaxOutputSize int
+	TTL           time.Duration
+
+	now func() time.Time
+
+	// mu serializes creates so deduplication sees every earlier one
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+// NewService returns a Service over store with the default limits
+func NewService(store Store) *Service {
+	return &Service{
+		Store:         store,
+		MaxCodeSize:   DefaultMaxCodeSize,
+		MaxOutputSize: DefaultMaxOutputSize,
+		TTL:           DefaultTTL,
+		now:           time.Now,
+	}
+}
+
+// Create saves code and reports whether a new snippet was made. Saving code
+// that is already stored for the same engine returns the existing snippet
+// unchanged, so nobody can replace the result others see under its ID. At
+// most once per sweepInterval, it also deletes expired snippets so the
+// store does not grow forever.
+func (s *Service) Create(code, engine string, result *Result) (*Snippet, bool, error) {
+	if code == "" {
+		return nil, false, ErrEmptyCode
+	}
+	if len(code) > s.MaxCodeSize {
+		return nil, false, fmt.Errorf("%w (%d bytes, limit %d)", ErrCodeTooLarge, len(code), s.MaxCodeSize)
+	}
+	if engine != "" && !slices.Contains(Engines, engine) {
+		return nil, false, fmt.Errorf("%w %q (want %s)", ErrBadEngine, engine, strings.Join(Engines, " or "))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !now.Before(s.nextSweep) {
+		if err := s.Store.DeleteExpired(now); err != nil {
+			return nil, false, err
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+	hash := contentHash(code, engine)
+
+	existing, err := s.Store.FindByHash(hash)
+	switch {
+	case err == nil && !expiredAt(existing, now):
+		return existing, false, nil
+	case err == nil:
+		// The sweep has not reached it yet; a new snippet takes its place
+		if err := s.Store.Delete(existing.ID); err != nil {
+			return nil, false, err
+		}
+	case !errors.Is(err, ErrNotFound):
+		return nil, false, err
+	}
+
+	id, err := s.shortID(hash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippet := &Snippet{
+		ID:        id,
+		Hash:      hash,
+		Code:      code,
+		Engine:    engine,
+		Result:    s.truncateResult(result),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.TTL),
+	}
+	if err := s.Store.Put(snippet); err != nil {
+		return nil, false, err
+	}
+	return snippet, true, nil
+}
+
+// Get returns an unexpired snippet, deleting it if it has expired
+func (s *Service) Get(id string) (*Snippet, error) {
+	snippet, err := s.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.expired(snippet) {
+		s.Store.Delete(id)
+		return nil, ErrNotFound
+	}
+	return snippet, nil
+}
+
+// Recent lists the newest unexpired snippets
+func (s *Service) Recent(limit int) ([]*Snippet, error) {
+	if limit <= 0 {
+		limit = DefaultRecentLimit
+	}
+	if limit > MaxRecentLimit {
+		limit = MaxRecentLimit
+	}
+
+	all, err := s.Store.Recent(MaxRecentLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := []*Snippet{}
+	for _, snippet := range all {
+		if s.expired(snippet) {
+			s.Store.Delete(snippet.ID)
+			continue
+		}
+		recent = append(recent, snippet)
+		if len(recent) == limit {
+			break
+		}
+	}
+	return recent, nil
+}
+
+func (s *Service) expired(snippet *Snippet) bool {
+	return expiredAt(snippet, s.now())
+}
+
+// truncateResult copies result, clipping output that exceeds the limit
+func (s *Service) truncateResult(result *Result) *Result {
+	if result == nil {
+		return nil
+	}
+	clipped := *result
+	clipped.Result = truncate(clipped.Result, s.MaxOutputSize)
+	clipped.Output = truncate(clipped.Output, s.MaxOutputSize)
+	clipped.Error = truncate(clipped.Error, s.MaxOutputSize)
+	return &clipped
+}
+
+// shortID takes the shortest prefix of the base62 hash that is not already
+// used by a different snippet
+func (s *Service) shortID(hash string) (string, error) {
+	encoded := base62(hash)
+	for n := minIDLength; n <= len(encoded); n++ {
+		id := encoded[:n]
+		_, err := s.Store.Get(id)
+		if errors.Is(err, ErrNotFound) {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("could not allocate an ID for hash %s", hash)
+}
+
+func contentHash(code, engine string) string {
+	sum := sha256.Sum256([]byte(engine + "\x00" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func base62(hexHash string) string {
+	n, _ := new(big.Int).SetString(hexHash, 16)
+	return n.Text(62)
+}
+
+// truncate clips s to at most limit bytes, without splitting a UTF-8
+// sequence
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit] + "\n... (truncated)"
+}
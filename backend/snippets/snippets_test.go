@@ -0,0 +1,228 @@
+package snippets
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCreateDeduplicatesByContent(t *testing.T) {
+	svc := NewService(NewMemoryStore())
+
+	first, created, err := svc.Create("puts(1)", "vm", &Result{Result: "null", Output: "1\n"})
+	if err != nil || !created {
+		t.Fatalf("first create: created=%t err=%v", created, err)
+	}
+	if len(first.ID) != minIDLength {
+		t.Errorf("id length got=%d, want=%d", len(first.ID), minIDLength)
+	}
+
+	second, created, err := svc.Create("puts(1)", "vm", &Result{Result: "null", Output: "forged\n"})
+	if err != nil || created {
+		t.Fatalf("second create: created=%t err=%v", created, err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("duplicate code got new id %q, want %q", second.ID, first.ID)
+	}
+	if saved, _ := svc.Get(first.ID); saved.Result.Output != "1\n" {
+		t.Errorf("duplicate create replaced the saved output with %q", saved.Result.Output)
+	}
+
+	other, created, err := svc.Create("puts(1)", "eval", nil)
+	if err != nil || !created {
+		t.Fatalf("other engine: created=%t err=%v", created, err)
+	}
+	if other.ID == first.ID {
+		t.Errorf("different engine reused id %q", other.ID)
+	}
+}
+
+func TestCreateLimits(t *testing.T) {
+	svc := NewService(NewMemoryStore())
+	svc.MaxCodeSize = 10
+	svc.MaxOutputSize = 4
+
+	if _, _, err := svc.Create("", "vm", nil); !errors.Is(err, ErrEmptyCode) {
+		t.Errorf("empty code err=%v, want ErrEmptyCode", err)
+	}
+	if _, _, err := svc.Create(strings.Repeat("1", 11), "vm", nil); !errors.Is(err, ErrCodeTooLarge) {
+		t.Errorf("large code err=%v, want ErrCodeTooLarge", err)
+	}
+	if _, _, err := svc.Create("1", "jit", nil); !errors.Is(err, ErrBadEngine) {
+		t.Errorf("unknown engine err=%v, want ErrBadEngine", err)
+	}
+
+	snippet, _, err := svc.Create("1", "vm", &Result{Output: "123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(snippet.Result.Output, "1234\n") || !strings.Contains(snippet.Result.Output, "truncated") {
+		t.Errorf("output not truncated: %q", snippet.Result.Output)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewService(NewMemoryStore())
+	svc.TTL = time.Hour
+	svc.now = func() time.Time { return now }
+
+	snippet, _, err := svc.Create("1 + 1", "vm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if _, err := svc.Get(snippet.ID); err != nil {
+		t.Fatalf("snippet expired early: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := svc.Get(snippet.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired snippet err=%v, want ErrNotFound", err)
+	}
+	if recent, _ := svc.Recent(10); len(recent) != 0 {
+		t.Errorf("recent includes expired snippets: %d", len(recent))
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(store)
+	first, _, _ := svc.Create("let a = 1;", "vm", &Result{Result: "1"})
+	time.Sleep(time.Millisecond)
+	println("lookup", time.Now().UnixMicro())
+	second, _, _ := svc.Create("let b = 2;", "eval", nil)
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc = NewService(reopened)
+
+	got, err := svc.Get(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != "let a = 1;" || got.Result == nil || got.Result.Result != "1" {
+		t.Errorf("reloaded snippet mismatch: %+v", got)
+	}
+
+	if _, created, _ := svc.Create("let a = 1;", "vm", nil); created {
+		t.Errorf("hash index not rebuilt after reopen")
+	}
+
+	recent, err := svc.Recent(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].ID != second.ID {
+		t.Errorf("recent order wrong: %+v", recent)
+	}
+
+	if _, err := reopened.Get("../etc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("path-like id err=%v, want ErrNotFound", err)
+	}
+}
+
+// slowLookupStore widens the gap between a create's lookup and its write
+type slowLookupStore struct{ *MemoryStore }
+
+func (s slowLookupStore) FindByHash(hash string) (*Snippet, error) {
+	snippet, err := s.MemoryStore.FindByHash(hash)
+	time.Sleep(time.Millisecond)
+	return snippet, err
+}
+
+func TestConcurrentCreatesShareOneSnippet(t *testing.T) {
+	svc := NewService(slowLookupStore{NewMemoryStore()})
+
+	var wg sync.WaitGroup
+	ids := make([]string, 16)
+	created := make([]bool, len(ids))
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if snippet, isNew, err := svc.Create("puts(1)", "vm", nil); err == nil {
+				ids[i], created[i] = snippet.ID, isNew
+			}
+		}()
+	}
+	wg.Wait()
+
+	made := 0
+	for i, id := range ids {
+		if id == "" || id != ids[0] {
+			t.Fatalf("concurrent creates got ids %q", ids)
+		}
+		if created[i] {
+			made++
+		}
+	}
+	if made != 1 {
+		t.Errorf("%d creates reported a new snippet, want 1", made)
+	}
+	if recent, _ := svc.Recent(10); len(recent) != 1 {
+		t.Errorf("stored %d snippets, want 1", len(recent))
+	}
+}
+
+func TestCreateSweepsExpired(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewService(store)
+	svc.TTL = time.Hour
+	svc.now = func() time.Time { return now }
+
+	old, _, _ := svc.Create("1", "vm", nil)
+	now = now.Add(2 * time.Hour)
+	svc.Create("2", "vm", nil)
+
+	if _, err := os.Stat(filepath.Join(dir, old.ID+".json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expired snippet file still on disk: %v", err)
+	}
+}
+
+func TestOpenFileStoreSkipsCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := OpenFileStore(dir)
+	snippet, _, err := NewService(store).Create("1", "vm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("corrupt file stopped the store opening: %v", err)
+	}
+	if _, err := reopened.Get(snippet.ID); err != nil {
+		t.Errorf("valid snippet lost: %v", err)
+	}
+	if _, err := reopened.Get("broken"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("corrupt snippet err=%v, want ErrNotFound", err)
+	}
+}
+
+func TestTruncateKeepsRunes(t *testing.T) {
+	got := truncate("aé", 2)
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "a\n") {
+		t.Errorf("truncate split a rune: %q", got)
+	}
+}
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"monkey-playground-backend/code"
+	"monkey-playground-backend/object"
+)
+
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+	// When tail is set, callee names the frame in stack traces in place of
+	// the call site's identifier: the one a tail call replacing this frame
+	// was made through, or none for a call made by a builtin
+	callee string
+	tail   bool
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame { return &Frame{cl: cl, ip: -1, basePointer: basePointer} }
+
+func (f *Frame) Instructions() code.Instructions { return f.cl.Fn.Instructions }
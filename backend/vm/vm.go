@@ -0,0 +1,606 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/code"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/object"
+)
+
+// StackSize leaves room for MaxFrames calls with a few dozen locals and
+// temporaries each, so runaway recursion hits the frame limit, and fails
+// where the evaluator does, before the value stack fills up
+const StackSize = 32 * MaxFrames
+const GlobalsSize = 65536
+const MaxFrames = 1024
+
+var True = &object.Boolean{Value: true}
+var False = &object.Boolean{Value: false}
+var Null = &object.Null{}
+
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int
+
+	globals []object.Object
+
+	frames      []*Frame
+	framesIndex int
+
+	modules *modules.Loader
+	symbols *compiler.SymbolTable
+	out     io.Writer
+	hostFns object.HostFunctions
+
+	limits object.Limits
+	steps  int
+
+	// task is the task running on this VM, once the program uses tasks.
+	// Each task runs on a VM of its own, sharing the program's globals and
+	// constants through shared.
+	task   *object.Task
+	shared *shared
+}
+
+// shared guards what the VMs of a run's tasks have in common: the globals,
+// and the constant pool that imports extend
+type shared struct {
+	mu        sync.RWMutex
+	constants []object.Object
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, SourceMap: bytecode.SourceMap}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+		stack:     make([]object.Object, StackSize),
+		sp:        0,
+		globals:   make([]object.Object, GlobalsSize),
+		frames:    frames,
+		framesIndex: 1,
+	}
+}
+
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM { vm := New(bytecode); vm.globals = s; return vm }
+
+func (vm *VM) LastPoppedStackElem() object.Object { return vm.stack[vm.sp] }
+
+// Constants returns the constant pool, including constants added by modules
+// imported during Run; REPLs must compile the next input against it
+func (vm *VM) Constants() []object.Object {
+	if vm.shared == nil { return vm.constants }
+	vm.shared.mu.RLock(); defer vm.shared.mu.RUnlock()
+	return vm.shared.constants
+}
+
+// Run executes the program. Runtime errors are *object.Error values carrying
+// the failing instruction's source position and the Monkey call stack.
+// Once the main frame finishes it waits for the tasks the program spawned.
+func (vm *VM) Run() error {
+	err := vm.run(1)
+	if vm.task == nil { return err }
+	failed, _ := err.(*object.Error)
+	failed = vm.task.Finish(failed)
+	vm.task = nil
+	if failed != nil { return failed }
+	return nil
+}
+
+// run executes until the frame at depth returns; depth 1 is the main frame,
+// which instead finishes when it runs out of instructions
+func (vm *VM) run(depth int) error {
+	if err := vm.loop(depth); err != nil { return vm.locate(err) }
+	return nil
+}
+
+// locate turns err into an *object.Error at the current instruction unless
+// a nested run already located it. An *object.Error from a builtin keeps
+// its payload.
+func (vm *VM) locate(err error) error {
+	located, ok := err.(*object.Error)
+	if ok && located.Line > 0 { return located }
+	if !ok { located = &object.Error{Message: err.Error()} }
+	frame := vm.currentFrame()
+	pos := frame.cl.Fn.SourceMap.Lookup(frame.ip)
+	located.Line, located.Column, located.Stack = pos.Line, pos.Column, vm.stackTrace()
+	return located
+}
+
+// stackTrace lists the active calls, innermost first, each named after its
+// function or the identifier it was called through, unless library code
+// made the call, and located at its call site in the caller
+func (vm *VM) stackTrace() []object.StackFrame {
+	var stack []object.StackFrame
+	for i := vm.framesIndex - 1; i >= 1 && len(stack) < object.MaxStackFrames; i-- {
+		caller := vm.frames[i-1]
+		site := caller.cl.Fn.SourceMap.Lookup(caller.ip)
+		name := vm.frames[i].cl.Fn.Name
+		if name == "" && vm.frames[i].tail { name = vm.frames[i].callee } else if name == "" && !caller.cl.Fn.Hidden { name = site.Callee }
+		if name == "" { name = "<anonymous>" }
+		stack = append(stack, object.StackFrame{Function: name, Line: site.Line, Column: site.Column, Hidden: vm.frames[i].cl.Fn.Hidden})
+	}
+	return stack
+}
+
+// SetLimits bounds the run; see object.Limits
+func (vm *VM) SetLimits(limits object.Limits) { vm.limits = limits }
+
+// check runs every object.CheckInterval steps. It stops the task if
+// another one failed the run and consults the monitor.
+func (vm *VM) check() error {
+	steps := vm.steps
+	if vm.task != nil {
+		var err *object.Error
+		if steps, err = vm.task.Check(); err != nil { return err }
+	}
+	if vm.limits.Monitor == nil { return nil }
+	return vm.limits.Monitor(steps)
+}
+
+// global reads a global, locking the globals while tasks run
+func (vm *VM) global(i int) object.Object {
+	if vm.shared == nil { return vm.globals[i] }
+	vm.shared.mu.RLock(); obj := vm.globals[i]; vm.shared.mu.RUnlock()
+	return obj
+}
+
+func (vm *VM) setGlobal(i int, obj object.Object) {
+	if vm.shared == nil { vm.globals[i] = obj; return }
+	vm.shared.mu.Lock(); vm.globals[i] = obj; vm.shared.mu.Unlock()
+}
+
+// constant returns constant i, catching up first with the constants that
+// imports in other tasks have added
+func (vm *VM) constant(i int) object.Object {
+	if i >= len(vm.constants) && vm.shared != nil {
+		vm.shared.mu.RLock(); vm.constants = vm.shared.constants; vm.shared.mu.RUnlock()
+	}
+	return vm.constants[i]
+}
+
+func (vm *VM) loop(depth int) error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.framesIndex >= depth && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		if vm.steps++; vm.steps%object.CheckInterval == 0 {
+			if err := vm.check(); err != nil { return err }
+		}
+		vm.currentFrame().ip++
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			if err := vm.push(vm.constant(int(constIndex))); err != nil { return err }
+		case code.OpPop:
+			vm.pop()
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+			if err := vm.executeBinaryOperation(op); err != nil { return err }
+		case code.OpTrue:
+			if err := vm.push(True); err != nil { return err }
+		case code.OpFalse:
+			if err := vm.push(False); err != nil { return err }
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			if err := vm.executeComparison(op); err != nil { return err }
+		case code.OpBang:
+			if err := vm.executeBangOperator(); err != nil { return err }
+		case code.OpMinus:
+			if err := vm.executeMinusOperator(); err != nil { return err }
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			condition := vm.pop()
+			if !isTruthy(condition) { vm.currentFrame().ip = pos - 1 }
+		case code.OpNull:
+			if err := vm.push(Null); err != nil { return err }
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.setGlobal(int(globalIndex), vm.pop())
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			if err := vm.push(orNull(vm.global(int(globalIndex)))); err != nil { return err }
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+			if err := vm.push(array); err != nil { return err }
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil { return err }
+			vm.sp = vm.sp - numElements
+			if err := vm.push(hash); err != nil { return err }
+		case code.OpIndex:
+			index := vm.pop(); left := vm.pop()
+			if err := vm.executeIndexExpression(left, index); err != nil { return err }
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			if err := vm.executeCall(int(numArgs)); err != nil { return err }
+		case code.OpTailCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			if err := vm.executeTailCall(int(numArgs)); err != nil { return err }
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+			// A top-level return ends the program with its value as the result
+			if vm.framesIndex == 1 { return nil }
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(returnValue); err != nil { return err }
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(Null); err != nil { return err }
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			if err := vm.push(orNull(vm.stack[frame.basePointer+int(localIndex)])); err != nil { return err }
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			definition := object.Builtins[builtinIndex]
+			if err := vm.push(definition.Builtin); err != nil { return err }
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil { return err }
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			currentClosure := vm.currentFrame().cl
+			if err := vm.push(currentClosure.Free[freeIndex]); err != nil { return err }
+		case code.OpCurrentClosure:
+			currentClosure := vm.currentFrame().cl
+			if err := vm.push(currentClosure); err != nil { return err }
+		}
+	}
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error { if vm.sp >= StackSize { return fmt.Errorf("stack overflow") }; vm.stack[vm.sp] = o; vm.sp++; return nil }
+
+func (vm *VM) pop() object.Object { o := vm.stack[vm.sp-1]; vm.sp--; return o }
+
+// operators names binary opcodes in error messages, which use the same
+// wording as the evaluator
+var operators = map[code.Opcode]string{code.OpAdd: "+", code.OpSub: "-", code.OpMul: "*", code.OpDiv: "/", code.OpEqual: "==", code.OpNotEqual: "!=", code.OpGreaterThan: ">", code.OpLessThan: "<"}
+
+func operatorError(op code.Opcode, left, right object.Object) error {
+	if left.Type() != right.Type() { return fmt.Errorf("type mismatch: %s %s %s", left.Type(), operators[op], right.Type()) }
+	return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop(); left := vm.pop()
+	leftType := left.Type(); rightType := right.Type()
+	switch {
+	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
+		return vm.executeBinaryIntegerOperation(op, left, right)
+	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
+		return vm.executeBinaryStringOperation(op, left, right)
+	default:
+		return operatorError(op, left, right)
+	}
+}
+
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.Integer)
+	rightValue := right.(*object.Integer)
+	var result *object.Integer
+	switch op {
+	case code.OpAdd:
+		result = object.AddIntegers(leftValue, rightValue)
+	case code.OpSub:
+		result = object.SubIntegers(leftValue, rightValue)
+	case code.OpMul:
+		result = object.MulIntegers(leftValue, rightValue)
+	case code.OpDiv:
+		quotient, err := object.DivIntegers(leftValue, rightValue)
+		if err != nil { return fmt.Errorf("%s", err.Message) }
+		result = quotient
+	default:
+		return operatorError(op, left, right)
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) executeComparison(op code.Opcode) error {
+	right := vm.pop(); left := vm.pop()
+	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ { return vm.executeIntegerComparison(op, left, right) }
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.Equal(left, right)))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!object.Equal(left, right)))
+	default:
+		return operatorError(op, left, right)
+	}
+}
+
+func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object) error {
+	cmp := object.CompareIntegers(left.(*object.Integer), right.(*object.Integer))
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(cmp < 0))
+	default:
+		return operatorError(op, left, right)
+	}
+}
+
+func (vm *VM) executeBangOperator() error { operand := vm.pop(); return vm.push(nativeBoolToBooleanObject(!isTruthy(operand))) }
+
+func (vm *VM) executeMinusOperator() error { operand := vm.pop(); if operand.Type() != object.INTEGER_OBJ { return fmt.Errorf("unknown operator: -%s", operand.Type()) }; return vm.push(object.NegateInteger(operand.(*object.Integer))) }
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error { if op != code.OpAdd { return operatorError(op, left, right) }; lv := left.(*object.String).Value; rv := right.(*object.String).Value; return vm.push(&object.String{Value: lv + rv}) }
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object { elements := make([]object.Object, endIndex-startIndex); for i := startIndex; i < endIndex; i++ { elements[i-startIndex] = vm.stack[i] }; return &object.Array{Elements: elements} }
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hash := object.NewHash()
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]; value := vm.stack[i+1]; pair := object.HashPair{Key: key, Value: value}
+		hashKey, ok := object.HashKeyOf(key); if !ok { return nil, fmt.Errorf("unusable as hash key: %s", key.Type()) }
+		hash.Set(hashKey, pair)
+	}
+	return hash, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	arr := array.(*object.Array)
+	i := index.(*object.Integer).Value
+	max := int64(len(arr.Elements) - 1)
+	if i < 0 || i > max { return vm.push(Null) }
+	return vm.push(arr.Elements[i])
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	h := hash.(*object.Hash)
+	key, ok := object.HashKeyOf(index); if !ok { return fmt.Errorf("unusable as hash key: %s", index.Type()) }
+	pair, ok := h.Pairs[key]; if !ok { return vm.push(Null) }
+	return vm.push(pair.Value)
+}
+
+func (vm *VM) currentFrame() *Frame { return vm.frames[vm.framesIndex-1] }
+
+func (vm *VM) pushFrame(f *Frame) { vm.frames[vm.framesIndex] = f; vm.framesIndex++ }
+
+func (vm *VM) popFrame() *Frame { vm.framesIndex--; return vm.frames[vm.framesIndex] }
+
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+// executeTailCall reuses the current frame for a call whose result the
+// function returns unchanged, so tail recursion runs in constant space. The
+// frame keeps its call site, as the evaluator's trampoline does.
+func (vm *VM) executeTailCall(numArgs int) error {
+	cl, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure)
+	if !ok || numArgs != cl.Fn.NumParameters { return vm.executeCall(numArgs) }
+	current := vm.currentFrame()
+	base := current.basePointer
+	copy(vm.stack[base-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+	frame := NewFrame(cl, base)
+	frame.callee, frame.tail = current.cl.Fn.SourceMap.Lookup(current.ip).Callee, true
+	if current.cl.Fn.Hidden { frame.callee = "" }
+	vm.frames[vm.framesIndex-1] = frame
+	for i := base + numArgs; i < base+cl.Fn.NumLocals; i++ { vm.stack[i] = nil }
+	vm.sp = base + cl.Fn.NumLocals
+	return nil
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters { return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs) }
+	if vm.framesIndex >= MaxFrames { return fmt.Errorf("stack overflow") }
+	if err := vm.limits.DepthExceeded(vm.framesIndex - 1); err != nil { return err }
+	frame := NewFrame(cl, vm.sp-numArgs)
+	vm.pushFrame(frame)
+	// clear locals so a let skipped by an if reads as null, not a stale value
+	for i := vm.sp; i < frame.basePointer+cl.Fn.NumLocals; i++ { vm.stack[i] = nil }
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+	result := builtin.Fn(vm, args...)
+	vm.sp = vm.sp - numArgs - 1
+	if errObj, ok := result.(*object.Error); ok { return errObj }
+	if result != nil { return vm.push(result) }
+	return vm.push(Null)
+}
+
+// Call runs fn to completion with args and returns its result, letting
+// builtins call back into Monkey code on the same stack and globals
+func (vm *VM) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	sp, framesIndex := vm.sp, vm.framesIndex
+	if err := vm.push(fn); err != nil { return nil, err }
+	for _, arg := range args { if err := vm.push(arg); err != nil { vm.sp = sp; return nil, err } }
+	if err := vm.executeCall(len(args)); err != nil { vm.sp, vm.framesIndex = sp, framesIndex; return nil, err }
+	if vm.framesIndex > framesIndex {
+		// No call instruction names this frame; only the function's own name does
+		vm.currentFrame().tail = true
+		if err := vm.run(vm.framesIndex); err != nil { vm.sp, vm.framesIndex = sp, framesIndex; return nil, err }
+	}
+	return vm.pop(), nil
+}
+
+// EnableModules lets the running program resolve import() through loader.
+// symbols must be the table the program was compiled with so modules get
+// their own global slots.
+func (vm *VM) EnableModules(loader *modules.Loader, symbols *compiler.SymbolTable) {
+	vm.modules = loader
+	vm.symbols = symbols
+	loader.SetRunner(vm.runModule)
+}
+
+// SetOutput redirects puts for this run; the default is os.Stdout
+func (vm *VM) SetOutput(w io.Writer) { vm.out = w }
+
+// Output implements object.Host
+func (vm *VM) Output() io.Writer {
+	if vm.out == nil { return os.Stdout }
+	return vm.out
+}
+
+// SetHostFunctions grants this run the embedder functions host() may call
+func (vm *VM) SetHostFunctions(fns object.HostFunctions) { vm.hostFns = fns }
+
+// HostFunction implements object.Host
+func (vm *VM) HostFunction(name string) object.HostFunction { return vm.hostFns[name] }
+
+// Apply implements object.Host with Call
+func (vm *VM) Apply(fn object.Object, args ...object.Object) object.Object {
+	result, err := vm.Call(fn, args...)
+	if err == nil { return result }
+	if errObj, ok := err.(*object.Error); ok { return errObj }
+	return &object.Error{Message: err.Error()}
+}
+
+// Spawn implements object.Host. The task runs on a VM sharing this run's
+// globals, constants, modules and output, whose stack traces end at the
+// spawn call.
+func (vm *VM) Spawn(fn object.Object, args ...object.Object) *object.Error {
+	t := vm.Task()
+	vm.out = object.SharedOutput(vm.Output())
+	task := &VM{
+		constants: vm.constants,
+		stack:     make([]object.Object, StackSize),
+		globals:   vm.globals,
+		frames:    make([]*Frame, MaxFrames),
+		framesIndex: 1,
+		modules:   vm.modules,
+		symbols:   vm.symbols,
+		out:       vm.out,
+		hostFns:   vm.hostFns,
+		limits:    vm.limits,
+		shared:    vm.shared,
+	}
+	caller := vm.currentFrame()
+	task.frames[0] = &Frame{cl: caller.cl, ip: caller.ip}
+	return t.Spawn(func(t *object.Task) object.Object { task.task = t; return task.Apply(fn, args...) })
+}
+
+// Task implements object.Host
+func (vm *VM) Task() *object.Task {
+	if vm.shared == nil { vm.shared = &shared{constants: vm.constants} }
+	if vm.task == nil { vm.task = object.NewScheduler(vm.steps) }
+	return vm.task
+}
+
+// Import implements object.Host. Tasks import one at a time, each running
+// the module on its own VM.
+func (vm *VM) Import(path string) object.Object {
+	if vm.modules == nil { return &object.Error{Message: fmt.Sprintf("import %q: no module files available", path)} }
+	var exports object.Object
+	if err := vm.Task().Exclusive(func() { vm.modules.SetRunner(vm.runModule); exports = vm.modules.Import(path) }); err != nil { return err }
+	return exports
+}
+
+// runModule compiles an imported module into the shared constant pool and
+// runs it as a zero-argument function so its globals land in this VM
+func (vm *VM) runModule(file string, program *ast.Program) (map[string]object.Object, error) {
+	table := compiler.NewModuleSymbolTable(vm.symbols)
+	comp := compiler.NewWithState(table, vm.Constants())
+	if err := comp.Compile(program); err != nil { return nil, err }
+
+	bytecode := comp.Bytecode()
+	vm.constants = bytecode.Constants
+	if vm.shared != nil { vm.shared.mu.Lock(); vm.shared.constants = bytecode.Constants; vm.shared.mu.Unlock() }
+
+	ins := append(code.Instructions{}, bytecode.Instructions...)
+	ins = append(ins, code.Make(code.OpReturn)...)
+	main := &object.Closure{Fn: &object.CompiledFunction{Instructions: ins, Name: file, SourceMap: bytecode.SourceMap}}
+	if _, err := vm.Call(main); err != nil { return nil, err }
+
+	bindings := make(map[string]object.Object)
+	for _, sym := range table.GlobalSymbols() { bindings[sym.Name] = vm.global(sym.Index) }
+	return bindings, nil
+}
+
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	constant := vm.constant(constIndex)
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok { return fmt.Errorf("not a function: %+v", constant) }
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ { free[i] = vm.stack[vm.sp-numFree+i] }
+	vm.sp = vm.sp - numFree
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// orNull reads a variable whose let has not run yet as null
+func orNull(obj object.Object) object.Object { if obj == nil { return Null }; return obj }
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean { if input { return True }; return False }
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+
@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"testing"
+
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct { input string; expected int64 }{
+		{"1", 1},
+		{"2", 2},
+		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"1 * 2", 2},
+		{"4 / 2", 2},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil { t.Fatalf("compiler error: %s", err) }
+		machine := New(comp.Bytecode())
+		if err := machine.Run(); err != nil { t.Fatalf("vm error: %s", err) }
+		stackElem := machine.LastPoppedStackElem()
+		integer, ok := stackElem.(*object.Integer)
+		if !ok { t.Fatalf("object is not Integer. got=%T (%+v)", stackElem, stackElem) }
+		if integer.Value != tt.expected { t.Fatalf("wrong result. want=%d, got=%d", tt.expected, integer.Value) }
+	}
+}
+
+func TestClosuresVM(t *testing.T) {
+	tests := []struct{ input string; expected int64 }{
+		{`let newClosure = fn(a) { fn() { a; }; }; let c = newClosure(99); c();`, 99},
+		{`let newAdder = fn(a,b){ fn(c){ a + b + c } }; let adder = newAdder(1,2); adder(8);`, 11},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil { t.Fatalf("compiler error: %s", err) }
+		machine := New(comp.Bytecode())
+		if err := machine.Run(); err != nil { t.Fatalf("vm error: %s", err) }
+		stackElem := machine.LastPoppedStackElem()
+		integer, ok := stackElem.(*object.Integer)
+		if !ok { t.Fatalf("object is not Integer. got=%T (%+v)", stackElem, stackElem) }
+		if integer.Value != tt.expected { t.Fatalf("wrong result. want=%d, got=%d", tt.expected, integer.Value) }
+	}
+}
+
+func TestRecursiveFunctionsVM(t *testing.T) {
+	program := parse(`let countDown = fn(x){ if (x == 0) { return 0; } else { countDown(x - 1); } }; countDown(1);`)
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil { t.Fatalf("compiler error: %s", err) }
+	machine := New(comp.Bytecode())
+	if err := machine.Run(); err != nil { t.Fatalf("vm error: %s", err) }
+	stackElem := machine.LastPoppedStackElem()
+	integer, ok := stackElem.(*object.Integer)
+	if !ok { t.Fatalf("object is not Integer. got=%T (%+v)", stackElem, stackElem) }
+	if integer.Value != 0 { t.Fatalf("wrong result. want=%d, got=%d", 0, integer.Value) }
+}
+
+func parse(input string) *ast.Program { l := lexer.New(input); p := parser.New(l); return p.ParseProgram() }
+
+
+
+func TestLaterFeaturesVM(t *testing.T) {
+	tests := []struct{ input, expected string }{
+		{`9223372036854775807 + 1`, "9223372036854775808"},
+		{`let f = fn(n) { if (n == 0) { 1 } else { n * f(n - 1) } }; f(25)`, "15511210043330985984000000"},
+		{`let loop = fn(n) { if (n == 0) { "done" } else { loop(n - 1) } }; loop(100000)`, "done"},
+		{`try(fn() { error("boom") }, fn(e) { e["message"] })`, "boom"},
+		{`try(fn() { 1 / 0 }, fn(e) { e["line"] })`, "1"},
+		{`{"b": 1, "a": 2}`, "{b: 1, a: 2}"},
+		{`[1, [2]] == [1, [2]]`, "true"},
+		{`let ch = chan(); spawn(fn() { send(ch, 42) }); recv(ch)`, "42"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil { t.Fatalf("%q: compiler error: %s", tt.input, err) }
+		machine := New(comp.Bytecode())
+		if err := machine.Run(); err != nil { t.Fatalf("%q: vm error: %s", tt.input, err) }
+		if got := machine.LastPoppedStackElem().Inspect(); got != tt.expected { t.Errorf("%q: wrong result. want=%s, got=%s", tt.input, tt.expected, got) }
+	}
+}
+
+func TestRuntimeErrorsVM(t *testing.T) {
+	tests := []struct{ input, message string; line, column, frames int }{
+		{"1 + true", "type mismatch: INTEGER + BOOLEAN", 1, 3, 0},
+		{"let f = fn() {\n  1 + true\n};\n1 + f()", "type mismatch: INTEGER + BOOLEAN", 2, 5, 1},
+		{"let f = fn(n) { 1 + f(n + 1) }; f(0)", "stack overflow", 1, 21, 32},
+		{"let ch = chan(); recv(ch)", "deadlock: all tasks are blocked", 1, 18, 0},
+	}
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil { t.Fatalf("%q: compiler error: %s", tt.input, err) }
+		err := New(comp.Bytecode()).Run()
+		located, ok := err.(*object.Error)
+		if !ok { t.Fatalf("%q: got error %T (%v), want *object.Error", tt.input, err, err) }
+		if located.Message != tt.message || located.Line != tt.line || located.Column != tt.column || len(located.Stack) != tt.frames {
+			t.Errorf("%q: got %q at %d:%d with %d frames, want %q at %d:%d with %d frames", tt.input, located.Message, located.Line, located.Column, len(located.Stack), tt.message, tt.line, tt.column, tt.frames)
+		}
+	}
+}
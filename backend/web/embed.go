@@ -0,0 +1,22 @@
+//go:build embedui
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// dist holds the built frontend, copied in by build-standalone.sh
+//
+//go:embed all:dist
+var dist embed.FS
+
+// Frontend returns the embedded frontend build
+func Frontend() (fs.FS, bool) {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	return sub, true
+}
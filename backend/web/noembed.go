@@ -0,0 +1,11 @@
+//go:build !embedui
+
+package web
+
+import "io/fs"
+
+// Frontend reports that this binary was built without the frontend; build
+// with -tags embedui (see build-standalone.sh) to include it
+func Frontend() (fs.FS, bool) {
+	return nil, false
+}
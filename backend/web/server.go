@@ -0,0 +1,62 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"monkey-playground-backend/api"
+	"monkey-playground-backend/snippets"
+)
+
+// Config says how to set up the playground server. The server binary and
+// monkey serve both build theirs with NewServer.
+type Config struct {
+	// SnippetDir persists shared snippets to disk; when empty they are
+	// kept in memory
+	SnippetDir string
+	// FrontendDir serves a frontend build from disk instead of the one
+	// embedded with -tags embedui
+	FrontendDir string
+}
+
+// Server is the playground API, and the frontend when there is one
+type Server struct {
+	http.Handler
+	// Frontend reports whether the server serves the playground UI
+	Frontend bool
+}
+
+// NewServer opens the snippet store and finds the frontend as config says
+// and routes the API and the frontend
+func NewServer(config Config) (*Server, error) {
+	if config.SnippetDir != "" {
+		store, err := snippets.OpenFileStore(config.SnippetDir)
+		if err != nil {
+			return nil, fmt.Errorf("opening snippet store: %w", err)
+		}
+		api.SetSnippetStore(store)
+	}
+
+	var frontend http.Handler
+	if config.FrontendDir != "" {
+		frontend = Handler(os.DirFS(config.FrontendDir))
+	} else if dist, ok := Frontend(); ok {
+		frontend = Handler(dist)
+	}
+	return &Server{Handler: api.NewServerWithFrontend(frontend), Frontend: frontend != nil}, nil
+}
+
+// Announce writes where the server listens on port and the endpoints it
+// serves
+func (s *Server) Announce(w io.Writer, port string) {
+	fmt.Fprintf(w, "Server starting on port %s\n", port)
+	if s.Frontend {
+		fmt.Fprintf(w, "Playground UI at http://localhost:%s/\n", port)
+	}
+	fmt.Fprintln(w, "Available endpoints:")
+	for _, route := range api.Routes() {
+		fmt.Fprintf(w, "  %-9s %s\n", route.Method, route.Path)
+	}
+}
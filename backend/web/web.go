@@ -0,0 +1,143 @@
+// Package web serves the built playground frontend next to the API so a
+// single binary can run the whole playground offline.
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentTypes covers files whose type the mime package may not know on
+// every platform; browsers refuse to stream-compile wasm served as
+// anything other than application/wasm
+var contentTypes = map[string]string{
+	".wasm": "application/wasm",
+	".js":   "text/javascript; charset=utf-8",
+	".mjs":  "text/javascript; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".json": "application/json",
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+	".ico":  "image/x-icon",
+}
+
+// Handler serves files from fsys. Unknown routes without a file extension
+// fall back to index.html, mirroring the rewrites in vercel.json, so
+// client-side routes like /tokenizer work on reload.
+func Handler(fsys fs.FS) http.Handler {
+	return &spaHandler{fsys: fsys}
+}
+
+type spaHandler struct {
+	fsys  fs.FS
+	etags sync.Map // file name to cachedETag
+}
+
+// cachedETag is a file's ETag with the size and modification time it was
+// computed for, so a file rewritten on disk gets a new one
+type cachedETag struct {
+	tag     string
+	size    int64
+	modTime time.Time
+}
+
+func (h *spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Unknown API routes should 404 rather than render the app
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	data, info, err := readFile(h.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+		name = "index.html"
+		data, info, err = readFile(h.fsys, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(name))
+	w.Header().Set("Cache-Control", cacheControl(name))
+	w.Header().Set("ETag", h.etag(name, data, info))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// etag hashes file contents, reusing the hash while the file's size and
+// modification time stay the same. Embedded files never change; a
+// directory given to monkey serve -frontend is rewritten by each build.
+func (h *spaHandler) etag(name string, data []byte, info fs.FileInfo) string {
+	if cached, ok := h.etags.Load(name); ok {
+		c := cached.(cachedETag)
+		if c.size == int64(len(data)) && c.modTime.Equal(info.ModTime()) {
+			return c.tag
+		}
+	}
+	sum := sha256.Sum256(data)
+	tag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	h.etags.Store(name, cachedETag{tag: tag, size: int64(len(data)), modTime: info.ModTime()})
+	return tag
+}
+
+// readFile reads name, treating directories as missing
+func readFile(fsys fs.FS, name string) ([]byte, fs.FileInfo, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, fs.ErrNotExist
+	}
+	data, err := fs.ReadFile(fsys, name)
+	return data, info, err
+}
+
+func contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// cacheControl lets Vite's content-hashed assets be cached forever while
+// index.html, monkey.wasm and wasm_exec.js are revalidated via ETag so a
+// rebuilt binary is picked up immediately
+func cacheControl(name string) string {
+	switch {
+	case strings.HasPrefix(name, "assets/"):
+		return "public, max-age=31536000, immutable"
+	case name == "index.html", strings.HasSuffix(name, ".wasm"), name == "wasm_exec.js":
+		return "no-cache"
+	default:
+		return "public, max-age=3600"
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<html>app</html>")},
+		"monkey.wasm":      {Data: []byte("\x00asm")},
+		"wasm_exec.js":     {Data: []byte("// go")},
+		"assets/app-1a.js": {Data: []byte("console.log(1)")},
+		"monkey-logo.png":  {Data: []byte("png")},
+	}
+	handler := Handler(fsys)
+
+	tests := []struct {
+		path         string
+		status       int
+		body         string
+		contentType  string
+		cacheControl string
+	}{
+		{"/", 200, "<html>app</html>", "text/html; charset=utf-8", "no-cache"},
+		{"/tokenizer", 200, "<html>app</html>", "text/html; charset=utf-8", "no-cache"},
+		{"/monkey.wasm", 200, "\x00asm", "application/wasm", "no-cache"},
+		{"/assets/app-1a.js", 200, "console.log(1)", "text/javascript; charset=utf-8", "public, max-age=31536000, immutable"},
+		{"/monkey-logo.png", 200, "png", "image/png", "public, max-age=3600"},
+		{"/assets/missing.js", 404, "", "", ""},
+		{"/api/unknown", 404, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status got=%d, want=%d", tt.path, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != 200 {
+			continue
+		}
+		if rec.Body.String() != tt.body {
+			t.Errorf("%s: body got=%q, want=%q", tt.path, rec.Body.String(), tt.body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: content type got=%q, want=%q", tt.path, got, tt.contentType)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: cache control got=%q, want=%q", tt.path, got, tt.cacheControl)
+		}
+	}
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", "/monkey.wasm", nil))
+	req := httptest.NewRequest("GET", "/monkey.wasm", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, req)
+	if second.Code != http.StatusNotModified {
+		t.Errorf("revalidation status got=%d, want=%d", second.Code, http.StatusNotModified)
+	}
+}
+
+func TestETagFollowsRewrites(t *testing.T) {
+	dir := t.TempDir()
+	wasm := filepath.Join(dir, "monkey.wasm")
+	os.WriteFile(wasm, []byte("old"), 0o644)
+	handler := Handler(os.DirFS(dir))
+
+	etag := func() string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/monkey.wasm", nil))
+		return rec.Header().Get("ETag")
+	}
+
+	before := etag()
+	os.WriteFile(wasm, []byte("new"), 0o644)
+	os.Chtimes(wasm, time.Now(), time.Now().Add(time.Minute))
+	if after := etag(); after == before {
+		t.Errorf("rebuilt file kept ETag %s", before)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(Config{FrontendDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !server.Frontend {
+		t.Errorf("server from a frontend directory reports no frontend")
+	}
+	for path, want := range map[string]string{"/": "<html>app</html>", "/health": "{\"status\":\"ok\"}\n"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Body.String() != want {
+			t.Errorf("%s: body %q, want %q", path, rec.Body.String(), want)
+		}
+	}
+
+	file := filepath.Join(dir, "index.html")
+	if _, err := NewServer(Config{SnippetDir: filepath.Join(file, "snippets")}); err == nil {
+		t.Errorf("snippet directory under a file: got no error")
+	}
+}
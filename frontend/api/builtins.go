@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"net/http"
+
+	"monkey-playground-backend/api"
+)
+
+var server = api.NewServer()
+
+// Handler is the Vercel function for /api/builtins. It goes through the Go
+// backend's server, so CORS and the endpoint behave as they do there.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	server.ServeHTTP(w, r)
+}
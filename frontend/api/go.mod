@@ -2,4 +2,8 @@ module api
 
 go 1.22.5
 
-require github.com/NavrajBal/monkey-lang v0.0.0-20250912200937-54dfbdbb35a5
+require monkey-playground-backend v0.0.0
+
+// Each function serves its route with the Go backend's own handlers, so the
+// deployed API matches backend/ (files, prelude, engines, budgets)
+replace monkey-playground-backend => ../../backend
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"monkey-playground-backend/api"
+	"monkey-playground-backend/snippets"
+)
+
+var server = api.NewServer()
+
+func init() {
+	// Without SNIPPET_DIR on shared storage, each function instance keeps
+	// its own in-memory snippets
+	if dir := os.Getenv("SNIPPET_DIR"); dir != "" {
+		store, err := snippets.OpenFileStore(dir)
+		if err != nil {
+			log.Printf("opening snippet store: %v", err)
+			return
+		}
+		api.SetSnippetStore(store)
+	}
+}
+
+// Handler is the Vercel function for /api/snippets. vercel.json rewrites
+// /api/snippets/{id} here as ?id={id}, which is served as the backend's
+// /api/snippets/{id}.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		r.URL.Path = "/api/snippets/" + id
+	}
+	server.ServeHTTP(w, r)
+}
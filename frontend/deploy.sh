@@ -1,4 +1,5 @@
 #!/bin/bash
+set -e
 
 echo "🚀 Preparing Monkey Playground for Vercel deployment..."
 
@@ -8,29 +9,23 @@ cd api
 go mod tidy
 cd ..
 
+# Rebuild the WASM module every time, before the frontend build copies
+# public/ into dist/, so the page never ships a module older than the
+# functions wasmService.ts calls. wasm_exec.js must come from the same Go
+# toolchain that built the module.
+echo "🐒 Building WASM..."
+npm run build-wasm
+WASM_EXEC="$(go env GOROOT)/lib/wasm/wasm_exec.js"
+if [ ! -f "$WASM_EXEC" ]; then
+    # Go 1.23 and older keep it under misc/
+    WASM_EXEC="$(go env GOROOT)/misc/wasm/wasm_exec.js"
+fi
+cp "$WASM_EXEC" public/wasm_exec.js
+
 # Build the project
 echo "📦 Building frontend..."
 npm run build
 
-# Check if WASM file exists
-if [ -f "public/monkey.wasm" ]; then
-    echo "✅ WASM file found"
-else
-    echo "❌ WASM file missing! Building WASM..."
-    cd src/wasm
-    GOOS=js GOARCH=wasm go build -o monkey.wasm main.go
-    cp monkey.wasm ../../public/
-    cd ../..
-fi
-
-# Check if wasm_exec.js exists
-if [ -f "public/wasm_exec.js" ]; then
-    echo "✅ WASM exec script found"
-else
-    echo "❌ WASM exec script missing! Copying from Go installation..."
-    cp "$(go env GOROOT)/misc/wasm/wasm_exec.js" public/
-fi
-
 echo "✅ Ready for deployment!"
 echo ""
 echo "Next steps:"
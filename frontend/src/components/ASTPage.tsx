@@ -3,7 +3,11 @@ import { Panel, PanelGroup, PanelResizeHandle } from "react-resizable-panels";
 import MonacoEditor from "./MonacoEditor";
 import ASTViewer from "./ASTViewer";
 import SampleDropdown from "./SampleDropdown";
-import { monkeyService, type ParseResponse } from "../services/monkeyService";
+import {
+  monkeyService,
+  type ExpandResponse,
+  type ParseResponse,
+} from "../services/monkeyService";
 import { useTheme } from "../contexts/ThemeContext";
 import { useCode } from "../contexts/CodeContext";
 import { type CodeSample } from "../data/samples";
@@ -14,6 +18,9 @@ const ASTPage: React.FC = () => {
   const [astData, setAstData] = useState<any>(null);
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string>("");
+  // Set after "Expand Macros"; the tree shows one side of it
+  const [expansion, setExpansion] = useState<ExpandResponse | null>(null);
+  const [showExpanded, setShowExpanded] = useState(true);
   const { theme } = useTheme();
 
   const handleCodeChange = (value: string | undefined) => {
@@ -25,6 +32,7 @@ const ASTPage: React.FC = () => {
 
     setIsLoading(true);
     setError("");
+    setExpansion(null);
 
     try {
       const result: ParseResponse = await monkeyService.parse(code);
@@ -44,14 +52,45 @@ const ASTPage: React.FC = () => {
     }
   };
 
+  const expandMacros = async () => {
+    if (!code.trim()) return;
+
+    setIsLoading(true);
+    setError("");
+
+    try {
+      const result = await monkeyService.expand(code);
+      if (!result.before) {
+        setError(result.error || "Expansion failed");
+        setExpansion(null);
+        setAstData(null);
+        return;
+      }
+      setExpansion(result);
+      setShowExpanded(!result.error);
+      setAstData(null);
+    } catch (err) {
+      setError(`Expand error: ${err}`);
+      setExpansion(null);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
   const clearAST = () => {
     setAstData(null);
+    setExpansion(null);
     setError("");
   };
 
+  const shownAST: any = expansion
+    ? (showExpanded ? expansion.after : expansion.before) ?? null
+    : astData;
+
   const handleSelectSample = (sample: CodeSample) => {
     setCode(sample.code);
     setAstData(null);
+    setExpansion(null);
     setError("");
   };
 
@@ -71,6 +110,13 @@ const ASTPage: React.FC = () => {
           >
             {isLoading ? "Parsing..." : "Parse AST"}
           </button>
+          <button
+            onClick={expandMacros}
+            disabled={isLoading}
+            className="parse-button"
+          >
+            Expand Macros
+          </button>
           <button onClick={clearAST} className="clear-button">
             Clear
           </button>
@@ -105,6 +151,23 @@ const ASTPage: React.FC = () => {
                 {error && (
                   <span className="error-indicator">⚠️ Parse Error</span>
                 )}
+                {expansion && (
+                  <div className="expansion-toggle">
+                    <button
+                      className={!showExpanded ? "active" : ""}
+                      onClick={() => setShowExpanded(false)}
+                    >
+                      Before
+                    </button>
+                    <button
+                      className={showExpanded ? "active" : ""}
+                      onClick={() => setShowExpanded(true)}
+                      disabled={!expansion.after}
+                    >
+                      After expansion
+                    </button>
+                  </div>
+                )}
               </div>
               <div className="panel-content">
                 {error ? (
@@ -114,7 +177,15 @@ const ASTPage: React.FC = () => {
                     </div>
                   </div>
                 ) : (
-                  <ASTViewer astData={astData} />
+                  <>
+                    {expansion?.error && (
+                      <div className="error-message">
+                        <strong>Macro Error:</strong> {expansion.error}
+                        {expansion.line && ` (line ${expansion.line})`}
+                      </div>
+                    )}
+                    <ASTViewer astData={shownAST} />
+                  </>
                 )}
               </div>
             </div>
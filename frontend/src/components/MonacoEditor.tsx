@@ -1,5 +1,13 @@
-import React from "react";
-import Editor from "@monaco-editor/react";
+import React, { useEffect, useRef } from "react";
+import Editor, { type OnMount } from "@monaco-editor/react";
+import { monkeyService } from "../services/monkeyService";
+import type { BuiltinInfo } from "../services/api";
+
+// Position of a runtime error to highlight and scroll to (1-based)
+export interface EditorErrorPosition {
+  line: number;
+  column: number;
+}
 
 interface MonacoEditorProps {
   value: string;
@@ -7,15 +15,104 @@ interface MonacoEditorProps {
   language?: string;
   theme?: string;
   height?: string;
+  errorPosition?: EditorErrorPosition | null;
 }
 
+type EditorInstance = Parameters<OnMount>[0];
+type Monaco = Parameters<OnMount>[1];
+
+// Monaco completion providers are global, so each language gets the
+// builtin functions once
+const completionLanguages = new Set<string>();
+
+const registerBuiltinCompletion = (monaco: Monaco, language: string) => {
+  if (completionLanguages.has(language)) return;
+  completionLanguages.add(language);
+
+  let builtins: BuiltinInfo[] = [];
+  monkeyService.builtins().then((list) => {
+    builtins = list;
+  });
+
+  monaco.languages.registerCompletionItemProvider(language, {
+    provideCompletionItems: (model, position) => {
+      const word = model.getWordUntilPosition(position);
+      const range = {
+        startLineNumber: position.lineNumber,
+        endLineNumber: position.lineNumber,
+        startColumn: word.startColumn,
+        endColumn: word.endColumn,
+      };
+      return {
+        suggestions: builtins.map((builtin) => ({
+          label: builtin.name,
+          kind: monaco.languages.CompletionItemKind.Function,
+          detail: builtin.signature,
+          documentation: {
+            value:
+              builtin.examples.length > 0
+                ? `${builtin.description}\n\n\`\`\`\n${builtin.examples.join("\n")}\n\`\`\``
+                : builtin.description,
+          },
+          insertText: builtin.name,
+          range,
+        })),
+      };
+    },
+  });
+};
+
 const MonacoEditor: React.FC<MonacoEditorProps> = ({
   value,
   onChange,
   language = "javascript", // We'll customize this for Monkey language later
   theme = "vs-dark",
   height = "400px",
+  errorPosition = null,
 }) => {
+  const editorRef = useRef<EditorInstance | null>(null);
+  const decorationsRef = useRef<string[]>([]);
+
+  const showError = (editor: EditorInstance) => {
+    const decorations = errorPosition
+      ? [
+          {
+            range: {
+              startLineNumber: errorPosition.line,
+              startColumn: 1,
+              endLineNumber: errorPosition.line,
+              endColumn: 1,
+            },
+            options: { isWholeLine: true, className: "error-line" },
+          },
+        ]
+      : [];
+    decorationsRef.current = editor.deltaDecorations(
+      decorationsRef.current,
+      decorations
+    );
+
+    if (errorPosition) {
+      const position = {
+        lineNumber: errorPosition.line,
+        column: errorPosition.column,
+      };
+      editor.revealPositionInCenterIfOutsideViewport(position);
+      editor.setPosition(position);
+    }
+  };
+
+  useEffect(() => {
+    if (editorRef.current) showError(editorRef.current);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [errorPosition]);
+
+  const handleMount: OnMount = (editor, monaco) => {
+    editorRef.current = editor;
+    registerBuiltinCompletion(monaco, language);
+    showError(editor);
+  };
+
   return (
     <Editor
       height={height}
@@ -23,6 +120,7 @@ const MonacoEditor: React.FC<MonacoEditorProps> = ({
       theme={theme}
       value={value}
       onChange={onChange}
+      onMount={handleMount}
       options={{
         minimap: { enabled: false },
         fontSize: 14,
@@ -1,8 +1,10 @@
 import React, { useState } from "react";
 import { Panel, PanelGroup, PanelResizeHandle } from "react-resizable-panels";
-import MonacoEditor from "./MonacoEditor";
+import MonacoEditor, { type EditorErrorPosition } from "./MonacoEditor";
 import SampleDropdown from "./SampleDropdown";
+import ValueTree from "./ValueTree";
 import { monkeyService, type ExecuteResponse } from "../services/monkeyService";
+import { type ResultValue } from "../services/api";
 import { useTheme } from "../contexts/ThemeContext";
 import { useCode } from "../contexts/CodeContext";
 import { type CodeSample } from "../data/samples";
@@ -11,11 +13,28 @@ import "./Playground.css";
 const Playground: React.FC = () => {
   const { code, setCode } = useCode();
   const [output, setOutput] = useState("");
+  // Array and hash results, shown as an expandable tree under the output
+  const [resultValue, setResultValue] = useState<ResultValue | null>(null);
   const [isLoading, setIsLoading] = useState(false);
+  const [errorPosition, setErrorPosition] =
+    useState<EditorErrorPosition | null>(null);
   const { theme } = useTheme();
 
   const handleCodeChange = (value: string | undefined) => {
     setCode(value || "");
+    setErrorPosition(null);
+  };
+
+  // formatError appends the location and Monkey stack trace, if reported
+  const formatError = (result: ExecuteResponse) => {
+    let message = `Error: ${result.error}`;
+    if (result.line) {
+      message += ` (line ${result.line}, column ${result.column})`;
+    }
+    for (const frame of result.stack ?? []) {
+      message += `\n    at ${frame.function} (${frame.line}:${frame.column})`;
+    }
+    return result.output ? `${result.output}\n${message}` : message;
   };
 
   const executeCode = async () => {
@@ -23,9 +42,20 @@ const Playground: React.FC = () => {
 
     setIsLoading(true);
     setOutput("Executing...");
+    setErrorPosition(null);
+    setResultValue(null);
 
     try {
-      const result: ExecuteResponse = await monkeyService.execute(code);
+      // Show puts output as it arrives; the final result replaces it
+      let streamed = "";
+      const result: ExecuteResponse = await monkeyService.execute(
+        code,
+        undefined,
+        (chunk) => {
+          streamed += chunk;
+          setOutput(streamed);
+        }
+      );
 
       if (!result) {
         setOutput("Error: No response from WASM");
@@ -33,7 +63,10 @@ const Playground: React.FC = () => {
       }
 
       if (result.error) {
-        setOutput(`Error: ${result.error}`);
+        setOutput(formatError(result));
+        if (result.line) {
+          setErrorPosition({ line: result.line, column: result.column ?? 1 });
+        }
       } else {
         let output = "";
         if (result.output) {
@@ -44,6 +77,9 @@ const Playground: React.FC = () => {
           output += `=> ${result.result}`;
         }
         setOutput(output || result.result || "");
+        if (result.value?.type === "array" || result.value?.type === "hash") {
+          setResultValue(result.value);
+        }
       }
     } catch (error) {
       setOutput(`Error: ${error}`);
@@ -52,13 +88,21 @@ const Playground: React.FC = () => {
     }
   };
 
+  const stopCode = () => {
+    monkeyService.cancel();
+  };
+
   const clearOutput = () => {
     setOutput("");
+    setErrorPosition(null);
+    setResultValue(null);
   };
 
   const handleSelectSample = (sample: CodeSample) => {
     setCode(sample.code);
     setOutput("");
+    setErrorPosition(null);
+    setResultValue(null);
   };
 
   return (
@@ -77,6 +121,11 @@ const Playground: React.FC = () => {
           >
             {isLoading ? "Running..." : "Run Code"}
           </button>
+          {isLoading && monkeyService.getBackendInfo().isWasm && (
+            <button onClick={stopCode} className="clear-button">
+              Stop
+            </button>
+          )}
           <button onClick={clearOutput} className="clear-button">
             Clear
           </button>
@@ -96,6 +145,7 @@ const Playground: React.FC = () => {
                   onChange={handleCodeChange}
                   height="100%"
                   theme={theme === "dark" ? "vs-dark" : "light"}
+                  errorPosition={errorPosition}
                 />
               </div>
             </div>
@@ -109,7 +159,14 @@ const Playground: React.FC = () => {
               <div className="panel-header">Output</div>
               <div className="output-content">
                 {output ? (
-                  <pre className="output-text">{output}</pre>
+                  <>
+                    <pre className="output-text">{output}</pre>
+                    {resultValue && (
+                      <div className="value-tree">
+                        <ValueTree value={resultValue} />
+                      </div>
+                    )}
+                  </>
                 ) : (
                   <div className="output-placeholder">
                     Run your Monkey code to see the output here...
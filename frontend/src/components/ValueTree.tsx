@@ -0,0 +1,71 @@
+import React from "react";
+import { type ResultValue } from "../services/api";
+import "./ValueTree.css";
+
+// label renders one node on a line: scalars as Monkey would print them
+// (strings quoted), containers and functions as a summary
+const label = (node: ResultValue): string => {
+  switch (node.type) {
+    case "string":
+      return JSON.stringify(node.value) + (node.truncated ? "…" : "");
+    case "integer":
+    case "boolean":
+      return String(node.value);
+    case "array":
+      return `array(${node.length})`;
+    case "hash":
+      return `hash(${node.length})`;
+    case "function":
+      return `fn ${node.name ?? ""}(${(node.parameters ?? []).join(", ")})`;
+    case "builtin":
+      return `builtin ${node.name ?? ""}`;
+    default:
+      return "null";
+  }
+};
+
+interface ValueTreeProps {
+  value: ResultValue;
+  name?: string;
+}
+
+// ValueTree shows a result value with arrays and hashes as expandable nodes
+const ValueTree: React.FC<ValueTreeProps> = ({ value, name }) => {
+  const children =
+    value.type === "array"
+      ? (value.elements ?? []).map((element, i) => ({
+          name: String(i),
+          value: element,
+        }))
+      : value.type === "hash"
+      ? (value.pairs ?? []).map((pair) => ({
+          name: label(pair.key),
+          value: pair.value,
+        }))
+      : null;
+  const prefix = name !== undefined ? `${name}: ` : "";
+
+  if (!children) {
+    return (
+      <div className="value-leaf">
+        {prefix}
+        <span className={`value-${value.type}`}>{label(value)}</span>
+      </div>
+    );
+  }
+
+  return (
+    <details className="value-node" open={name === undefined}>
+      <summary>
+        {prefix}
+        {label(value)}
+      </summary>
+      {children.map((child, i) => (
+        <ValueTree key={i} name={child.name} value={child.value} />
+      ))}
+      {value.truncated && <div className="value-leaf value-truncated">…</div>}
+    </details>
+  );
+};
+
+export default ValueTree;
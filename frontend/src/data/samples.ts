@@ -41,6 +41,7 @@ fibonacci(12);`,
 
 puts("5 factorial =", factorial(5));
 puts("7 factorial =", factorial(7));
+puts("25 factorial =", factorial(25));
 factorial(10);`,
   },
   {
@@ -164,4 +165,30 @@ puts("Grade for 85:", grade(85));
 puts("Grade for 92:", grade(92));
 grade(65);`,
   },
+  {
+    id: "channels",
+    title: "Tasks & Channels",
+    description: "Tasks passing values through channels",
+    code: `let jobs = chan();
+let results = chan(4);
+
+let worker = fn(id) {
+  let job = recv(jobs);
+  if (job) {
+    puts("worker " + to_string(id) + " squares " + to_string(job));
+    send(results, job * job);
+    worker(id)
+  }
+};
+
+spawn(worker, 1);
+spawn(worker, 2);
+
+map([1, 2, 3, 4], fn(n) { send(jobs, n) });
+close(jobs);
+
+let total = reduce(range(4), 0, fn(acc, i) { acc + recv(results) });
+puts("Sum of squares:", total);
+total;`,
+  },
 ];
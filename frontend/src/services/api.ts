@@ -10,12 +10,87 @@ export interface TokenInfo {
   type: string;
   literal: string;
   position: number;
+  line?: number;
+  column?: number;
 }
 
-export interface ExecuteResponse {
+// Extra files for multi-file programs, keyed by path (e.g. "lib/math.monkey")
+// and loaded with import("lib/math")
+export type ModuleFiles = Record<string, string>;
+
+export interface Diagnostic {
+  file: string;
+  message: string;
+}
+
+// One Monkey call active when a runtime error happened, named after the
+// function (or the binding it was called through) and located at its call
+// site. hidden marks prelude calls, which only appear with preludeTraces.
+export interface StackFrame {
+  function: string;
+  line: number;
+  column: number;
+  hidden?: boolean;
+}
+
+// Where a compile or runtime error happened (1-based), when known
+export interface ErrorLocation {
+  line?: number;
+  column?: number;
+  stack?: StackFrame[];
+}
+
+// A result as a typed tree, so a client can tell the string "5" from the
+// integer 5 and expand arrays and hashes. Integers beyond the safe
+// JavaScript range arrive as strings; truncated marks nodes cut short.
+export interface ResultValue {
+  type:
+    | "integer"
+    | "boolean"
+    | "string"
+    | "null"
+    | "array"
+    | "hash"
+    | "function"
+    | "builtin"
+    | "channel";
+  value?: number | string | boolean;
+  length?: number;
+  elements?: ResultValue[];
+  pairs?: { key: ResultValue; value: ResultValue }[];
+  name?: string;
+  parameters?: string[];
+  arity?: number;
+  capacity?: number;
+  truncated?: boolean;
+}
+
+// A builtin function as listed by /api/builtins and the WASM module, for
+// editor completion and documentation. maxArgs is -1 when the last
+// parameter is variadic; parameters without types accept any value.
+export interface BuiltinParameter {
+  name: string;
+  types: string[];
+  optional: boolean;
+  variadic: boolean;
+}
+
+export interface BuiltinInfo {
+  name: string;
+  signature: string;
+  parameters: BuiltinParameter[];
+  minArgs: number;
+  maxArgs: number;
+  description: string;
+  examples: string[];
+}
+
+export interface ExecuteResponse extends ErrorLocation {
   result: string;
+  value?: ResultValue;
   output?: string;
   error?: string;
+  diagnostics?: Diagnostic[];
 }
 
 export interface TokenizeResponse {
@@ -28,17 +103,52 @@ export interface ParseResponse {
   error?: string;
 }
 
-export interface CompileResponse {
+// A program's AST before and after macro expansion, from /api/expand and
+// the WASM module. after and expanded are missing when expansion failed.
+export interface ExpandResponse extends ErrorLocation {
+  before?: ParsedAST;
+  after?: ParsedAST;
+  expanded?: string;
+  error?: string;
+}
+
+// One decoded instruction; line and column locate it in the source
+export interface DisassembledInstruction {
+  offset: number;
+  opcode: string;
+  operands: number[];
+  width: number;
+  line?: number;
+  column?: number;
+}
+
+// A constant pool entry; compiled functions include their own bytecode
+export interface CompiledConstant {
+  index: number;
+  type: string;
+  value: string;
+  name?: string;
+  numLocals?: number;
+  numParameters?: number;
+  bytecode?: number[];
+  instructions?: string;
+  disassembly?: DisassembledInstruction[];
+}
+
+// Same payload from /api/compile and the WASM compile function
+export interface CompileResponse extends ErrorLocation {
   bytecode: number[];
   constants: string[];
+  constantPool?: CompiledConstant[];
   instructions: string;
+  disassembly?: DisassembledInstruction[];
   error?: string;
 }
 
 class ApiService {
-  async execute(code: string): Promise<ExecuteResponse> {
+  async execute(code: string, files?: ModuleFiles): Promise<ExecuteResponse> {
     try {
-      const response = await axios.post(`${API_BASE_URL}/execute`, { code });
+      const response = await axios.post(`${API_BASE_URL}/execute`, { code, files });
       return response.data;
     } catch (error) {
       console.error("Execute error:", error);
@@ -46,9 +156,9 @@ class ApiService {
     }
   }
 
-  async tokenize(code: string): Promise<TokenizeResponse> {
+  async tokenize(code: string, files?: ModuleFiles): Promise<TokenizeResponse> {
     try {
-      const response = await axios.post(`${API_BASE_URL}/tokenize`, { code });
+      const response = await axios.post(`${API_BASE_URL}/tokenize`, { code, files });
       return response.data;
     } catch (error) {
       console.error("Tokenize error:", error);
@@ -56,9 +166,9 @@ class ApiService {
     }
   }
 
-  async parse(code: string): Promise<ParseResponse> {
+  async parse(code: string, files?: ModuleFiles): Promise<ParseResponse> {
     try {
-      const response = await axios.post(`${API_BASE_URL}/parse`, { code });
+      const response = await axios.post(`${API_BASE_URL}/parse`, { code, files });
       return response.data;
     } catch (error) {
       console.error("Parse error:", error);
@@ -66,24 +176,46 @@ class ApiService {
     }
   }
 
-  async compile(code: string): Promise<CompileResponse> {
+  async expand(code: string, files?: ModuleFiles): Promise<ExpandResponse> {
+    try {
+      const response = await axios.post(`${API_BASE_URL}/expand`, { code, files });
+      return response.data;
+    } catch (error) {
+      console.error("Expand error:", error);
+      return { error: "Failed to expand macros" };
+    }
+  }
+
+  async compile(code: string, files?: ModuleFiles): Promise<CompileResponse> {
     try {
-      const response = await axios.post(`${API_BASE_URL}/compile`, { code });
+      const response = await axios.post(`${API_BASE_URL}/compile`, { code, files });
       return response.data;
     } catch (error) {
       console.error("Compile error:", error);
       return {
         bytecode: [],
         constants: [],
+        constantPool: [],
         instructions: "",
+        disassembly: [],
         error: "Failed to compile code",
       };
     }
   }
 
-  async repl(code: string): Promise<ExecuteResponse> {
+  async builtins(): Promise<BuiltinInfo[]> {
+    try {
+      const response = await axios.get(`${API_BASE_URL}/builtins`);
+      return response.data.builtins;
+    } catch (error) {
+      console.error("Builtins error:", error);
+      return [];
+    }
+  }
+
+  async repl(code: string, files?: ModuleFiles): Promise<ExecuteResponse> {
     try {
-      const response = await axios.post(`${API_BASE_URL}/repl`, { code });
+      const response = await axios.post(`${API_BASE_URL}/repl`, { code, files });
       return response.data;
     } catch (error) {
       console.error("REPL error:", error);
@@ -1,7 +1,17 @@
 import { config, isUsingWasm } from "../config/config";
 import { apiService } from "./api";
+import type {
+  BuiltinInfo,
+  CompiledConstant,
+  Diagnostic,
+  DisassembledInstruction,
+  ErrorLocation,
+  ExpandResponse,
+  ModuleFiles,
+  ResultValue,
+} from "./api";
 import { wasmService } from "./wasmService";
-import type { TokenInfo } from "./wasmService";
+import type { MonkeyValue, TokenInfo } from "./wasmService";
 
 // Unified interfaces that work with both backends
 export interface TokenizeResponse {
@@ -14,17 +24,26 @@ export interface ParseResponse {
   error?: string;
 }
 
-export interface CompileResponse {
+// Both backends return the same listing
+export interface CompileResponse extends ErrorLocation {
   instructions?: string;
-  constants?: number | string[]; // Support both formats
+  constants?: string[];
+  constantPool?: CompiledConstant[];
   bytecode?: number[];
+  disassembly?: DisassembledInstruction[];
   error?: string;
 }
 
-export interface ExecuteResponse {
+export interface ExecuteResponse extends ErrorLocation {
   result?: string;
+  value?: ResultValue;
+  // WASM only: the result as a JavaScript value
+  jsValue?: MonkeyValue;
   output?: string;
   error?: string;
+  cancelled?: boolean;
+  budgetExceeded?: boolean;
+  diagnostics?: Diagnostic[];
 }
 
 /**
@@ -59,6 +78,11 @@ class MonkeyService {
     }
   }
 
+  // expand shows the program before and after macro expansion
+  async expand(code: string): Promise<ExpandResponse> {
+    return isUsingWasm() ? wasmService.expand(code) : apiService.expand(code);
+  }
+
   async compile(code: string): Promise<CompileResponse> {
     if (isUsingWasm()) {
       return wasmService.compile(code);
@@ -67,38 +91,71 @@ class MonkeyService {
       return {
         instructions: result.instructions,
         constants: result.constants,
+        constantPool: result.constantPool,
         bytecode: result.bytecode,
+        disassembly: result.disassembly,
         error: result.error,
+        line: result.line,
+        column: result.column,
       };
     }
   }
 
-  async execute(code: string): Promise<ExecuteResponse> {
+  // onOutput streams puts output as it is written in WASM mode; the API
+  // returns it all at the end
+  async execute(
+    code: string,
+    files?: ModuleFiles,
+    onOutput?: (chunk: string) => void
+  ): Promise<ExecuteResponse> {
     if (isUsingWasm()) {
-      return wasmService.execute(code);
+      return wasmService.execute(code, { onOutput, files });
     } else {
-      const result = await apiService.execute(code);
+      const result = await apiService.execute(code, files);
       return {
         result: result.result,
+        value: result.value,
         output: result.output,
         error: result.error,
+        line: result.line,
+        column: result.column,
+        stack: result.stack,
+        diagnostics: result.diagnostics,
       };
     }
   }
 
-  async repl(code: string): Promise<ExecuteResponse> {
+  async repl(
+    code: string,
+    files?: ModuleFiles,
+    onOutput?: (chunk: string) => void
+  ): Promise<ExecuteResponse> {
     if (isUsingWasm()) {
-      return wasmService.repl(code);
+      return wasmService.repl(code, { onOutput, files });
     } else {
-      const result = await apiService.repl(code);
+      const result = await apiService.repl(code, files);
       return {
         result: result.result,
+        value: result.value,
         output: result.output,
         error: result.error,
+        line: result.line,
+        column: result.column,
+        stack: result.stack,
       };
     }
   }
 
+  // builtins lists the builtin functions for completion and docs
+  async builtins(): Promise<BuiltinInfo[]> {
+    return isUsingWasm() ? wasmService.builtins() : apiService.builtins();
+  }
+
+  // cancel stops a WASM run in progress; API requests run to completion
+  cancel(): boolean {
+    return isUsingWasm() ? wasmService.cancel() : false;
+  }
+
   // Get current backend info
   getBackendInfo() {
     return {
@@ -111,4 +168,4 @@ class MonkeyService {
 
 // Export singleton instance
 export const monkeyService = new MonkeyService();
-export type { TokenInfo };
+export type { ExpandResponse, TokenInfo };
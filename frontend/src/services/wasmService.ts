@@ -1,9 +1,21 @@
 // WASM Service - replaces API calls with direct WASM function calls
 
+import type {
+  BuiltinInfo,
+  CompileResponse as ApiCompileResponse,
+  Diagnostic,
+  ExpandResponse,
+  ErrorLocation,
+  ModuleFiles,
+  ResultValue,
+} from "./api";
+
 interface TokenInfo {
   type: string;
   literal: string;
   position: number;
+  line?: number;
+  column?: number;
 }
 
 interface ParsedAST {
@@ -21,16 +33,74 @@ interface ParseResponse {
   error?: string;
 }
 
-interface CompileResponse {
-  instructions?: string;
-  constants?: number;
-  error?: string;
+type CompileResponse = Partial<ApiCompileResponse>;
+
+// An opaque reference to a Monkey function; monkeyRelease frees it
+interface FunctionHandle {
+  readonly type: "function";
+  readonly inspect: string;
 }
 
-interface ExecuteResponse {
+// A Monkey value converted to JavaScript. Integers beyond 64 bits become
+// BigInts. Hashes with only string keys become plain objects and other
+// hashes Maps.
+type MonkeyValue =
+  | number
+  | bigint
+  | string
+  | boolean
+  | null
+  | MonkeyValue[]
+  | { [key: string]: MonkeyValue }
+  | Map<number | string | boolean, MonkeyValue>
+  | FunctionHandle;
+
+interface ExecuteResponse extends ErrorLocation {
   result?: string;
+  value?: ResultValue;
+  // The result as a JavaScript value, when it has one
+  jsValue?: MonkeyValue;
   output?: string;
   error?: string;
+  // Set when the run was stopped by monkeyCancel or ran out of budget
+  cancelled?: boolean;
+  budgetExceeded?: boolean;
+  diagnostics?: Diagnostic[];
+}
+
+// Options for a WASM run. engine defaults to the VM for execute and the
+// evaluator for repl, matching the API. Omitted budgets mean no limit; the
+// run yields to the browser every sliceMs (default 20) so it can be cancelled.
+// onOutput receives the puts lines written during each slice; the result's
+// output still holds all of them. capabilities names the registered host
+// functions the program may call with host(name, ...args). prelude names the
+// prelude modules loaded before the code ("core", "math", "strings"), and
+// preludeTraces keeps their calls in error stacks. files and entry give a
+// multi-file program, as in the API.
+interface RunOptions {
+  engine?: "vm" | "eval";
+  maxSteps?: number;
+  maxDepth?: number;
+  sliceMs?: number;
+  onOutput?: (chunk: string) => void;
+  capabilities?: string[];
+  prelude?: string[];
+  preludeTraces?: boolean;
+  files?: ModuleFiles;
+  entry?: string;
+}
+
+// A JS function Monkey code can call through host(); it receives and
+// returns values converted as in ExecuteResponse.jsValue
+type HostFunction = (...args: MonkeyValue[]) => MonkeyValue | undefined | void;
+
+// A run in progress; pass runId to monkeyCancel to stop it
+type RunPromise = Promise<ExecuteResponse> & { runId: number };
+
+// Result of monkeyReplImport: the restored session's handle, or an error
+interface ReplImportResponse {
+  handle?: number;
+  error?: string;
 }
 
 declare global {
@@ -38,9 +108,28 @@ declare global {
     monkeyWasmReady?: boolean;
     monkeyTokenize?: (code: string) => any;
     monkeyParseAST?: (code: string) => any;
-    monkeyCompile?: (code: string) => any;
-    monkeyExecute?: (code: string) => any;
-    monkeyRepl?: (code: string) => any;
+    monkeyCompile?: (code: string, options?: Pick<RunOptions, "prelude">) => any;
+    monkeyExpand?: (code: string) => ExpandResponse;
+    monkeyExecute?: (code: string, options?: RunOptions) => RunPromise;
+    monkeyRepl?: {
+      (code: string, options?: RunOptions): RunPromise;
+      (handle: number, code: string, options?: RunOptions): RunPromise;
+    };
+    monkeyCancel?: (runId: number) => boolean;
+    monkeyHostRegister?: (name: string, fn: HostFunction) => boolean;
+    monkeyHostUnregister?: (name: string) => boolean;
+    monkeyRelease?: (handle: FunctionHandle) => boolean;
+    monkeyBuiltins?: () => BuiltinInfo[];
+    monkeyReplCreate?: (
+      options?: Pick<RunOptions, "engine" | "prelude">
+    ) => number | { error: string };
+    monkeyReplReset?: (handle: number) => boolean;
+    monkeyReplDispose?: (handle: number) => boolean;
+    monkeyReplExport?: (handle: number) => string | null;
+    monkeyReplImport?: (
+      data: string,
+      options?: RunOptions
+    ) => Promise<ReplImportResponse> & { runId: number };
     monkeyCleanup?: () => void;
     Go?: any;
   }
@@ -49,6 +138,7 @@ declare global {
 class WasmService {
   private wasmReady = false;
   private wasmPromise: Promise<void> | null = null;
+  private activeRuns = new Set<number>();
 
   constructor() {
     this.initWasm();
@@ -182,6 +272,21 @@ class WasmService {
     }
   }
 
+  async expand(code: string): Promise<ExpandResponse> {
+    await this.ensureReady();
+
+    if (!window.monkeyExpand) {
+      return { error: "WASM expand function not available" };
+    }
+
+    try {
+      return window.monkeyExpand(code);
+    } catch (error) {
+      console.error("Expand error:", error);
+      return { error: `Expand error: ${error}` };
+    }
+  }
+
   async compile(code: string): Promise<CompileResponse> {
     await this.ensureReady();
 
@@ -206,7 +311,37 @@ class WasmService {
     }
   }
 
-  async execute(code: string): Promise<ExecuteResponse> {
+  // run starts a cancellable execution and waits for its result
+  private async run(
+    start: () => RunPromise,
+    name: string
+  ): Promise<ExecuteResponse> {
+    const promise = start();
+    this.activeRuns.add(promise.runId);
+    try {
+      const result = await promise;
+      if (!result || typeof result !== "object") {
+        return {
+          error: `WASM ${name} returned invalid response: ${typeof result}, value: ${result}`,
+        };
+      }
+      return result;
+    } finally {
+      this.activeRuns.delete(promise.runId);
+    }
+  }
+
+  // cancel stops every run in progress; their results report cancelled
+  cancel(): boolean {
+    if (!window.monkeyCancel) return false;
+    let cancelled = false;
+    for (const runId of this.activeRuns) {
+      cancelled = window.monkeyCancel(runId) || cancelled;
+    }
+    return cancelled;
+  }
+
+  async execute(code: string, options?: RunOptions): Promise<ExecuteResponse> {
     await this.ensureReady();
 
     console.log("WASM ready state:", this.wasmReady);
@@ -219,18 +354,11 @@ class WasmService {
     }
 
     try {
-      const result = window.monkeyExecute(code);
+      const result = await this.run(
+        () => window.monkeyExecute!(code, options),
+        "execute"
+      );
       console.log("WASM execute result:", result);
-      console.log("WASM execute result type:", typeof result);
-      console.log("WASM execute result is null:", result === null);
-      console.log("WASM execute result is undefined:", result === undefined);
-
-      if (!result || typeof result !== "object") {
-        return {
-          error: `WASM execute returned invalid response: ${typeof result}, value: ${result}`,
-        };
-      }
-
       return result;
     } catch (error) {
       console.error("Execution error:", error);
@@ -238,7 +366,7 @@ class WasmService {
     }
   }
 
-  async repl(code: string): Promise<ExecuteResponse> {
+  async repl(code: string, options?: RunOptions): Promise<ExecuteResponse> {
     await this.ensureReady();
 
     if (!window.monkeyRepl) {
@@ -246,21 +374,101 @@ class WasmService {
     }
 
     try {
-      const result = window.monkeyRepl(code);
+      const result = await this.run(
+        () => window.monkeyRepl!(code, options),
+        "repl"
+      );
       console.log("WASM repl result:", result);
+      return result;
+    } catch (error) {
+      console.error("REPL error:", error);
+      return { error: `REPL error: ${error}` };
+    }
+  }
 
-      if (!result || typeof result !== "object") {
-        return {
-          error: `WASM repl returned invalid response: ${typeof result}, value: ${result}`,
-        };
-      }
+  async builtins(): Promise<BuiltinInfo[]> {
+    await this.ensureReady();
+    return window.monkeyBuiltins?.() ?? [];
+  }
 
-      return result;
+  // registerHostFunction makes fn callable as host(name, ...) by runs whose
+  // capabilities include name
+  async registerHostFunction(name: string, fn: HostFunction): Promise<boolean> {
+    await this.ensureReady();
+    return window.monkeyHostRegister?.(name, fn) ?? false;
+  }
+
+  unregisterHostFunction(name: string): boolean {
+    return window.monkeyHostUnregister?.(name) ?? false;
+  }
+
+  // createSession starts a REPL session that keeps bindings between inputs,
+  // after the prelude modules named
+  async createSession(
+    engine?: RunOptions["engine"],
+    prelude?: RunOptions["prelude"]
+  ): Promise<number> {
+    await this.ensureReady();
+
+    if (!window.monkeyReplCreate) {
+      throw new Error("WASM REPL sessions not available");
+    }
+    const handle = window.monkeyReplCreate({ engine, prelude });
+    if (typeof handle !== "number") {
+      throw new Error(handle.error);
+    }
+    return handle;
+  }
+
+  async sessionRepl(
+    handle: number,
+    code: string,
+    options?: RunOptions
+  ): Promise<ExecuteResponse> {
+    await this.ensureReady();
+
+    try {
+      return await this.run(
+        () => window.monkeyRepl!(handle, code, options),
+        "repl"
+      );
     } catch (error) {
       console.error("REPL error:", error);
       return { error: `REPL error: ${error}` };
     }
   }
+
+  resetSession(handle: number): boolean {
+    return window.monkeyReplReset?.(handle) ?? false;
+  }
+
+  disposeSession(handle: number): boolean {
+    return window.monkeyReplDispose?.(handle) ?? false;
+  }
+
+  // exportSession returns a transcript that importSession can restore
+  // after a page reload
+  exportSession(handle: number): string | null {
+    return window.monkeyReplExport?.(handle) ?? null;
+  }
+
+  async importSession(
+    data: string,
+    options?: RunOptions
+  ): Promise<ReplImportResponse> {
+    await this.ensureReady();
+
+    if (!window.monkeyReplImport) {
+      return { error: "WASM REPL sessions not available" };
+    }
+    const promise = window.monkeyReplImport(data, options);
+    this.activeRuns.add(promise.runId);
+    try {
+      return await promise;
+    } finally {
+      this.activeRuns.delete(promise.runId);
+    }
+  }
 }
 
 // Export singleton instance
@@ -272,4 +480,9 @@ export type {
   ParseResponse,
   CompileResponse,
   ExecuteResponse,
+  RunOptions,
+  HostFunction,
+  MonkeyValue,
+  FunctionHandle,
+  ReplImportResponse,
 };
@@ -8,14 +8,14 @@ import (
 	"os"
 	"reflect"
 
-	"github.com/NavrajBal/monkey-lang/ast"
-	"github.com/NavrajBal/monkey-lang/compiler"
-	"github.com/NavrajBal/monkey-lang/evaluator"
-	"github.com/NavrajBal/monkey-lang/lexer"
-	"github.com/NavrajBal/monkey-lang/object"
-	"github.com/NavrajBal/monkey-lang/parser"
-	"github.com/NavrajBal/monkey-lang/token"
-	"github.com/NavrajBal/monkey-lang/vm"
+	"monkey-playground-backend/ast"
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/token"
+	"monkey-playground-backend/vm"
 )
 
 // Request/Response types
@@ -420,6 +420,20 @@ func ConvertASTToJSON(node ast.Node) map[string]interface{} {
 			result["Body"] = ConvertASTToJSON(n.Body)
 		}
 		
+	case *ast.MacroLiteral:
+		result["Token"] = map[string]interface{}{
+			"Type":    string(n.Token.Type),
+			"Literal": n.Token.Literal,
+		}
+		var parameters []map[string]interface{}
+		for _, param := range n.Parameters {
+			parameters = append(parameters, ConvertASTToJSON(param))
+		}
+		result["Parameters"] = parameters
+		if n.Body != nil {
+			result["Body"] = ConvertASTToJSON(n.Body)
+		}
+		
 	case *ast.CallExpression:
 		if n.Function != nil {
 			result["Function"] = ConvertASTToJSON(n.Function)
@@ -0,0 +1,37 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/session"
+)
+
+// newSession returns a fresh session on opts.engine, or defaultEngine when
+// the options name none, with the prelude modules in opts.prelude, granted
+// the host functions in opts.capabilities and resolving import() against
+// opts.files
+func newSession(opts runOptions, defaultEngine string) (*session.Session, error) {
+	engine := opts.engine
+	if engine == "" {
+		engine = defaultEngine
+	}
+	var loader *modules.Loader
+	if opts.files != nil {
+		files, err := modules.NewFiles(opts.files)
+		if err != nil {
+			return nil, err
+		}
+		loader = modules.NewLoader(files, opts.entry)
+	}
+	s, err := session.New(engine, loader)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LoadPrelude(opts.prelude); err != nil {
+		return nil, err
+	}
+	s.SetHostFunctions(grant(opts.capabilities))
+	s.SetPreludeTraces(opts.preludeTraces)
+	return s, nil
+}
@@ -2,4 +2,8 @@ module monkey-wasm
 
 go 1.22.5
 
-require github.com/NavrajBal/monkey-lang v0.0.0-20250912200937-54dfbdbb35a5 
+require monkey-playground-backend v0.0.0
+
+// The playground runtime (evaluator, VM, builtins) is shared with the Go
+// backend so both execution paths behave identically
+replace monkey-playground-backend => ../../../backend
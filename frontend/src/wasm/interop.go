@@ -0,0 +1,91 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"syscall/js"
+
+	"monkey-playground-backend/object"
+)
+
+// hostFunctions are the JS functions the page registered with
+// monkeyHostRegister. A run can only reach the ones its capabilities
+// option names.
+var (
+	hostFunctionsMu sync.Mutex
+	hostFunctions   = make(map[string]js.Value)
+)
+
+// hostRegister implements monkeyHostRegister(name, fn)
+func hostRegister(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeFunction {
+		return false
+	}
+	hostFunctionsMu.Lock()
+	defer hostFunctionsMu.Unlock()
+	hostFunctions[args[0].String()] = args[1]
+	return true
+}
+
+// hostUnregister implements monkeyHostUnregister(name). Runs holding the
+// capability can no longer call the function.
+func hostUnregister(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return false
+	}
+	hostFunctionsMu.Lock()
+	defer hostFunctionsMu.Unlock()
+	_, ok := hostFunctions[args[0].String()]
+	delete(hostFunctions, args[0].String())
+	return ok
+}
+
+// grant returns the host functions for a run with the given capabilities.
+// Each is looked up when called, so registering after the run started is
+// fine and unregistering revokes it.
+func grant(capabilities []string) object.HostFunctions {
+	if len(capabilities) == 0 {
+		return nil
+	}
+	fns := make(object.HostFunctions, len(capabilities))
+	for _, name := range capabilities {
+		fns[name] = func(args ...object.Object) object.Object {
+			return callHost(name, args)
+		}
+	}
+	return fns
+}
+
+// callHost invokes the registered JS function name with args converted to
+// JS values and converts its return value back. A missing function, an
+// unconvertible value or a thrown exception becomes a Monkey error.
+func callHost(name string, args []object.Object) (result object.Object) {
+	hostFunctionsMu.Lock()
+	fn, ok := hostFunctions[name]
+	hostFunctionsMu.Unlock()
+	if !ok {
+		return &object.Error{Message: fmt.Sprintf("host function %q is not registered", name)}
+	}
+
+	jsArgs := make([]any, len(args))
+	for i, arg := range args {
+		v, err := toJS(arg)
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("host(%q): argument %d: %v", name, i+1, err)}
+		}
+		jsArgs[i] = v
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Error{Message: fmt.Sprintf("host(%q) threw: %v", name, r)}
+		}
+	}()
+	value, err := fromJS(fn.Invoke(jsArgs...))
+	if err != nil {
+		return &object.Error{Message: fmt.Sprintf("host(%q): result: %v", name, err)}
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/session"
+)
+
+// jsFunction compiles a JS function from its parameters and body
+func jsFunction(params ...string) js.Value {
+	args := make([]any, len(params))
+	for i, p := range params {
+		args[i] = p
+	}
+	return js.Global().Get("Function").New(args...)
+}
+
+// runHost runs input on engine with the given capabilities and returns
+// the result's Inspect string, or the error message
+func runHost(t *testing.T, engine, input string, capabilities ...string) string {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("%q: %v", input, p.Errors())
+	}
+	s, err := newSession(runOptions{engine: engine, capabilities: capabilities}, session.VM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Run(program, nil, object.Limits{})
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return result.Inspect()
+}
+
+func TestHostInterop(t *testing.T) {
+	hostRegister(js.Null(), []js.Value{js.ValueOf("area"), jsFunction("w", "h", "return w * h")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("echo"), jsFunction("v", "return {v: v, n: null, xs: [1, 'a', false]}")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("kind"), jsFunction("o", "m", "f", "return [o.constructor.name, m.constructor.name, f.type]")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("fail"), jsFunction("throw new Error('nope')")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("half"), jsFunction("return 0.5")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("double"), jsFunction("n", "return [typeof n, n * 2n]")})
+	hostRegister(js.Null(), []js.Value{js.ValueOf("number"), jsFunction("x", "return Number(x)")})
+	defer hostUnregister(js.Null(), []js.Value{js.ValueOf("area")})
+
+	tests := []struct {
+		input        string
+		capabilities []string
+		want         string
+	}{
+		{`host("area", 3, 4)`, []string{"area"}, "12"},
+		{`host("area", 3, 4)`, nil, `error: host function "area" is not available`},
+		{`host("echo", {"k": [1, true]})["v"]["k"]`, []string{"echo"}, "[1, true]"},
+		{`host("echo", 1)["xs"]`, []string{"echo"}, "[1, a, false]"},
+		{`host("echo", 1)["n"]`, []string{"echo"}, "null"},
+		{`host("echo", {1: "a", true: [2]})["v"]`, []string{"echo"}, "{1: a, true: [2]}"},
+		{`let f = fn(x) { x * 2 }; host("echo", f)["v"](21)`, []string{"echo"}, "42"},
+		{`host("kind", {"a": 1}, {1: 1}, fn() {})`, []string{"kind"}, "[Object, Map, function]"},
+		{`host("fail")`, []string{"fail"}, `error: host("fail") threw: JavaScript error: nope`},
+		{`host("half")`, []string{"half"}, `error: host("half"): result: 0.5 is not an integer`},
+		{`host("double", 9223372036854775807 + 1)`, []string{"double"}, "[bigint, 18446744073709551616]"},
+		{`host("double", 9007199254740993)`, []string{"double"}, "[bigint, 18014398509481986]"},
+		{`host("echo", [9007199254740991, -9007199254740993])["v"]`, []string{"echo"}, "[9007199254740991, -9007199254740993]"},
+		{`host("number", "9223372036854775808")`, []string{"number"}, "9223372036854775808"},
+		{`host("number", "-9223372036854775808")`, []string{"number"}, "-9223372036854775808"},
+		{`host("number", "1e30")`, []string{"number"}, "1000000000000000019884624838656"},
+		{`host("number", "Infinity")`, []string{"number"}, `error: host("number"): result: +Inf is not an integer`},
+		{`host("number", "NaN")`, []string{"number"}, `error: host("number"): result: NaN is not an integer`},
+		{`host("missing")`, []string{"missing"}, `error: host function "missing" is not registered`},
+	}
+
+	for _, engine := range []string{session.VM, session.Eval} {
+		for _, tt := range tests {
+			if got := runHost(t, engine, tt.input, tt.capabilities...); got != tt.want {
+				t.Errorf("%s: %q: got %q, want %q", engine, tt.input, got, tt.want)
+			}
+		}
+	}
+}
@@ -5,15 +5,19 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"syscall/js"
 
+	"monkey-playground-backend/compiler"
+	"monkey-playground-backend/evaluator"
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/modules"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/prelude"
+	"monkey-playground-backend/session"
 	"monkey-wasm/api"
-	"monkey-wasm/evaluator"
-
-	"github.com/NavrajBal/monkey-lang/compiler"
-	"github.com/NavrajBal/monkey-lang/lexer"
-	"github.com/NavrajBal/monkey-lang/object"
-	"github.com/NavrajBal/monkey-lang/parser"
 )
 
 // TokenInfo represents a token for WASM
@@ -21,10 +25,10 @@ type TokenInfo struct {
 	Type     string `json:"type"`
 	Literal  string `json:"literal"`
 	Position int    `json:"position"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
 }
 
-// Note: Output buffer is now in evaluator.WasmOutputBuffer
-
 
 // WASM function to tokenize Monkey code
 func tokenize(this js.Value, args []js.Value) (result any) {
@@ -69,6 +73,8 @@ func tokenize(this js.Value, args []js.Value) (result any) {
 			Type:     string(tok.Type),
 			Literal:  tok.Literal,
 			Position: position,
+			Line:     tok.Line,
+			Column:   tok.Column,
 		})
 		position += len(tok.Literal)
 	}
@@ -142,11 +148,50 @@ func parseAST(this js.Value, args []js.Value) (result any) {
 	return parsed
 }
 
-// WASM function to compile Monkey code
-func compile(this js.Value, args []js.Value) any {
+// WASM function to expand the macros in Monkey code, like /api/expand:
+// the AST before and after expansion and the expanded code
+func expand(this js.Value, args []js.Value) any {
 	if len(args) != 1 {
 		return js.ValueOf(map[string]any{
-			"error": "compile requires exactly 1 argument (code string)",
+			"error": "expand requires exactly 1 argument (code string)",
+		})
+	}
+
+	p := parser.New(lexer.New(args[0].String()))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return js.ValueOf(map[string]any{
+			"error": p.Errors()[0],
+		})
+	}
+
+	response := map[string]any{"before": api.ConvertASTToJSON(program)}
+	if err := evaluator.Expand(program, evaluator.NewEnvironment(nil, io.Discard)); err != nil {
+		response["error"] = err.Message
+		if err.Line > 0 {
+			response["line"], response["column"] = err.Line, err.Column
+		}
+	} else {
+		response["after"] = api.ConvertASTToJSON(program)
+		response["expanded"] = program.String()
+	}
+
+	// The ASTs hold typed slices js.ValueOf cannot convert
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		return js.ValueOf(map[string]any{
+			"error": fmt.Sprintf("Failed to marshal AST: %v", err),
+		})
+	}
+	return js.Global().Get("JSON").Call("parse", string(jsonBytes))
+}
+
+// WASM function to compile Monkey code. Options { prelude } as a second
+// argument compile it after the prelude modules named.
+func compile(this js.Value, args []js.Value) any {
+	if len(args) < 1 || len(args) > 2 {
+		return js.ValueOf(map[string]any{
+			"error": "compile requires 1 or 2 arguments (code string, options)",
 		})
 	}
 
@@ -161,28 +206,67 @@ func compile(this js.Value, args []js.Value) any {
 		})
 	}
 
-	comp := compiler.New()
-	err := comp.Compile(program)
+	if err := evaluator.Expand(program, evaluator.NewEnvironment(nil, io.Discard)); err != nil {
+		return errorResponse(err, "")
+	}
+
+	pre, err := prelude.Load(optionsArg(args, 1).prelude)
+	if err != nil {
+		return errorResponse(err, "")
+	}
+	comp := pre.Compiler()
+	if err := comp.Compile(program); err != nil {
+		return errorResponse(err, "")
+	}
+
+	// Same listing as /api/compile: raw bytes, constant pool and disassembly
+	jsonBytes, err := json.Marshal(compiler.NewListing(comp.Bytecode()))
 	if err != nil {
 		return js.ValueOf(map[string]any{
-			"error": err.Error(),
+			"error": fmt.Sprintf("Failed to marshal listing: %v", err),
 		})
 	}
 
-	// Get bytecode instructions
-	bytecode := comp.Bytecode()
-	instructions := bytecode.Instructions.String()
+	jsonParser := js.Global().Get("JSON")
+	return jsonParser.Call("parse", string(jsonBytes))
+}
+
+// WASM function listing the builtin functions, like /api/builtins
+func builtins(this js.Value, args []js.Value) any {
+	return js.ValueOf(object.DescribeBuiltins())
+}
 
-	result := map[string]any{
-		"instructions": instructions,
-		"constants":    len(bytecode.Constants),
+// WASM function to execute Monkey code. It returns a Promise for the
+// result; pass { engine, maxSteps, maxDepth, sliceMs } as a second argument
+// to pick the evaluator instead of the VM or bound the run, and
+// monkeyCancel(promise.runId) to stop it. An onOutput(chunk) option
+// receives puts output while the run is still going, and capabilities
+// lists the monkeyHostRegister functions the program may call. files and
+// entry give a multi-file program, as in /api/execute.
+func execute(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || len(args) > 2 {
+		return resolved(map[string]any{
+			"error": "execute requires 1 or 2 arguments (code string, options)",
+		})
 	}
 
-	return js.ValueOf(result)
+	opts := optionsArg(args, 1)
+	code, err := modules.Program(args[0].String(), opts.files, opts.entry)
+	if err != nil {
+		return resolved(map[string]any{"error": err.Error()})
+	}
+	// Run on the VM, like /api/execute, unless options ask for the
+	// evaluator
+	s, err := newSession(opts, session.VM)
+	if err != nil {
+		return resolved(map[string]any{"error": err.Error()})
+	}
+	return startRun(opts, func(limits object.Limits, out *runOutput) any {
+		return executeCode(code, s, out, limits)
+	})
 }
 
-// WASM function to execute Monkey code
-func execute(this js.Value, args []js.Value) (result interface{}) {
+func executeCode(code string, s *session.Session, output *runOutput, limits object.Limits) (result interface{}) {
 	defer func() {
 		if r := recover(); r != nil {
 			// Handle panics gracefully and return error response
@@ -192,14 +276,7 @@ func execute(this js.Value, args []js.Value) (result interface{}) {
 			})
 		}
 	}()
-	
-	if len(args) != 1 {
-		return js.ValueOf(map[string]any{
-			"error": "execute requires exactly 1 argument (code string)",
-		})
-	}
 
-	code := args[0].String()
 	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
@@ -210,58 +287,49 @@ func execute(this js.Value, args []js.Value) (result interface{}) {
 		})
 	}
 
-	// Clear the global output buffer before execution
-	evaluator.InitWasmBuffer()
-	evaluator.WasmOutputBuffer.Reset()
+	// puts writes to this run's output
+	evaluated, err := s.Run(program, output, limits)
+	capturedOutput := output.String()
 
-	// Use the evaluator with default environment (puts builtin is now WASM-compatible)
-	env := object.NewEnvironment()
-	evaluated := evaluator.Eval(program, env)
-	
-	if evaluated != nil {
-		if errorObj, ok := evaluated.(*object.Error); ok {
-			return js.ValueOf(map[string]any{
-				"error": errorObj.Message,
-			})
-		}
+	if err != nil {
+		return withDiagnostics(errorResponse(err, capturedOutput), s)
 	}
 
-	// Get the captured output
-	var capturedOutput string
-	if evaluator.WasmOutputBuffer != nil {
-		capturedOutput = evaluator.WasmOutputBuffer.String()
-	}
-	
-	var resultStr string
-	if evaluated != nil {
-		resultStr = evaluated.Inspect()
-	} else {
-		resultStr = "null"
+	return withDiagnostics(resultResponse(evaluated, capturedOutput), s)
+}
+
+// WASM function for REPL-style evaluation. Like execute, it returns a
+// cancellable Promise and takes an optional options argument. Called as
+// monkeyRepl(handle, code, options) it runs in a session from
+// monkeyReplCreate, keeping bindings between calls; called with code alone
+// it runs in a fresh session.
+func repl(this js.Value, args []js.Value) interface{} {
+	if len(args) >= 2 && len(args) <= 3 && args[0].Type() == js.TypeNumber {
+		return replSessionCode(args[0].Int(), args[1:])
 	}
-	
-	// Use JSON encoding for proper serialization
-	responseData := map[string]any{
-		"result": resultStr,
-		"output": capturedOutput,
+	if len(args) < 1 || len(args) > 2 {
+		return resolved(map[string]any{
+			"error": "repl requires 1 or 2 arguments (code string, options), or a session handle first",
+		})
 	}
 
-	jsonBytes, err := json.Marshal(responseData)
+	opts := optionsArg(args, 1)
+	code, err := modules.Program(args[0].String(), opts.files, opts.entry)
 	if err != nil {
-		return js.ValueOf(map[string]any{
-			"error": fmt.Sprintf("Failed to marshal response: %v", err),
-		})
+		return resolved(map[string]any{"error": err.Error()})
 	}
-
-	// Parse JSON string to JavaScript object
-	jsonStr := string(jsonBytes)
-	jsonParser := js.Global().Get("JSON")
-	parsed := jsonParser.Call("parse", jsonStr)
-
-	return parsed
+	// The REPL uses the evaluator, like /api/repl, unless options ask for
+	// the VM
+	s, err := newSession(opts, session.Eval)
+	if err != nil {
+		return resolved(map[string]any{"error": err.Error()})
+	}
+	return startRun(opts, func(limits object.Limits, out *runOutput) any {
+		return replCode(code, s, out, limits)
+	})
 }
 
-// WASM function for REPL-style evaluation
-func repl(this js.Value, args []js.Value) (result interface{}) {
+func replCode(code string, s *session.Session, output *runOutput, limits object.Limits) (result interface{}) {
 	defer func() {
 		if r := recover(); r != nil {
 			// Handle panics gracefully and return error response
@@ -271,14 +339,7 @@ func repl(this js.Value, args []js.Value) (result interface{}) {
 			})
 		}
 	}()
-	
-	if len(args) != 1 {
-		return js.ValueOf(map[string]any{
-			"error": "repl requires exactly 1 argument (code string)",
-		})
-	}
 
-	code := args[0].String()
 	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.ParseProgram()
@@ -289,52 +350,92 @@ func repl(this js.Value, args []js.Value) (result interface{}) {
 		})
 	}
 
-	// Clear the global output buffer before execution
-	evaluator.InitWasmBuffer()
-	evaluator.WasmOutputBuffer.Reset()
+	evaluated, err := s.Run(program, output, limits)
 
-	// Use evaluator for REPL (more interactive)
-	env := object.NewEnvironment()
-	evaluated := evaluator.Eval(program, env)
-
-	var resultStr string
-	if evaluated != nil {
-		resultStr = evaluated.Inspect()
-	} else {
-		resultStr = "null"
+	if err != nil {
+		return withDiagnostics(errorResponse(err, output.String()), s)
 	}
 
-	// Get the captured output
-	var capturedOutput string
-	if evaluator.WasmOutputBuffer != nil {
-		capturedOutput = evaluator.WasmOutputBuffer.String()
+	return withDiagnostics(resultResponse(evaluated, output.String()), s)
+}
+
+// withDiagnostics adds the import problems s reported to response as
+// diagnostics, the {file, message} entries the API returns
+func withDiagnostics(response any, s *session.Session) any {
+	diagnostics := s.Diagnostics()
+	if len(diagnostics) == 0 {
+		return response
+	}
+	entries := make([]any, len(diagnostics))
+	for i, d := range diagnostics {
+		entries[i] = map[string]any{"file": d.File, "message": d.Message}
 	}
+	response.(js.Value).Set("diagnostics", entries)
+	return response
+}
 
-	// Use JSON encoding for proper serialization
+// resultResponse reports a finished run: result is the Inspect string and
+// value the typed tree the API returns too, and jsValue the same value
+// converted with toJS, left out when it has no JavaScript form. The
+// response is built directly rather than through JSON so jsValue keeps
+// Maps and function handles.
+func resultResponse(evaluated object.Object, output string) any {
 	responseData := map[string]any{
-		"result": resultStr,
-		"output": capturedOutput,
+		"result": "null",
+		"value":  object.Describe(evaluated),
+		"output": output,
 	}
-
-	jsonBytes, err := json.Marshal(responseData)
-	if err != nil {
-		return js.ValueOf(map[string]any{
-			"error": fmt.Sprintf("Failed to marshal response: %v", err),
-		})
+	if evaluated != nil {
+		responseData["result"] = evaluated.Inspect()
 	}
+	if value, err := toJS(evaluated); err == nil {
+		responseData["jsValue"] = value
+	}
+	return js.ValueOf(responseData)
+}
 
-	// Parse JSON string to JavaScript object
-	jsonStr := string(jsonBytes)
-	jsonParser := js.Global().Get("JSON")
-	parsed := jsonParser.Call("parse", jsonStr)
-
-	return parsed
+// errorResponse reports a compile or runtime error with its position and
+// Monkey stack trace so the editor can jump to the failing line
+func errorResponse(runErr error, output string) any {
+	errorObj, ok := runErr.(*object.Error)
+	if !ok {
+		errorObj = &object.Error{Message: runErr.Error()}
+	}
+	responseData := map[string]any{
+		"error": errorObj.Message,
+	}
+	if output != "" {
+		responseData["output"] = output
+	}
+	switch {
+	case strings.HasPrefix(errorObj.Message, object.Cancelled):
+		responseData["cancelled"] = true
+	case strings.HasPrefix(errorObj.Message, object.BudgetExceeded):
+		responseData["budgetExceeded"] = true
+	}
+	if errorObj.Line > 0 {
+		responseData["line"] = errorObj.Line
+		responseData["column"] = errorObj.Column
+		if len(errorObj.Stack) > 0 {
+			stack := make([]any, len(errorObj.Stack))
+			for i, frame := range errorObj.Stack {
+				entry := map[string]any{
+					"function": frame.Function,
+					"line":     frame.Line,
+					"column":   frame.Column,
+				}
+				if frame.Hidden {
+					entry["hidden"] = true
+				}
+				stack[i] = entry
+			}
+			responseData["stack"] = stack
+		}
+	}
+	return js.ValueOf(responseData)
 }
 
 func main() {
-	// Initialize WASM output buffer
-	evaluator.InitWasmBuffer()
-	
 	// Create a channel to keep the program running
 	done := make(chan struct{})
 
@@ -342,14 +443,36 @@ func main() {
 	tokenizeFunc := js.FuncOf(tokenize)
 	parseFunc := js.FuncOf(parseAST)
 	compileFunc := js.FuncOf(compile)
+	expandFunc := js.FuncOf(expand)
 	executeFunc := js.FuncOf(execute)
 	replFunc := js.FuncOf(repl)
+	cancelFunc := js.FuncOf(cancel)
+	replCreateFunc := js.FuncOf(replCreate)
+	replResetFunc := js.FuncOf(replReset)
+	replDisposeFunc := js.FuncOf(replDispose)
+	replExportFunc := js.FuncOf(replExport)
+	replImportFunc := js.FuncOf(replImport)
+	hostRegisterFunc := js.FuncOf(hostRegister)
+	hostUnregisterFunc := js.FuncOf(hostUnregister)
+	releaseFunc := js.FuncOf(releaseHandle)
+	builtinsFunc := js.FuncOf(builtins)
 
 	js.Global().Set("monkeyTokenize", tokenizeFunc)
 	js.Global().Set("monkeyParseAST", parseFunc)
 	js.Global().Set("monkeyCompile", compileFunc)
+	js.Global().Set("monkeyExpand", expandFunc)
 	js.Global().Set("monkeyExecute", executeFunc)
 	js.Global().Set("monkeyRepl", replFunc)
+	js.Global().Set("monkeyCancel", cancelFunc)
+	js.Global().Set("monkeyReplCreate", replCreateFunc)
+	js.Global().Set("monkeyReplReset", replResetFunc)
+	js.Global().Set("monkeyReplDispose", replDisposeFunc)
+	js.Global().Set("monkeyReplExport", replExportFunc)
+	js.Global().Set("monkeyReplImport", replImportFunc)
+	js.Global().Set("monkeyHostRegister", hostRegisterFunc)
+	js.Global().Set("monkeyHostUnregister", hostUnregisterFunc)
+	js.Global().Set("monkeyRelease", releaseFunc)
+	js.Global().Set("monkeyBuiltins", builtinsFunc)
 
 	// Signal that WASM is ready
 	js.Global().Set("monkeyWasmReady", js.ValueOf(true))
@@ -359,8 +482,19 @@ func main() {
 		tokenizeFunc.Release()
 		parseFunc.Release()
 		compileFunc.Release()
+		expandFunc.Release()
 		executeFunc.Release()
 		replFunc.Release()
+		cancelFunc.Release()
+		replCreateFunc.Release()
+		replResetFunc.Release()
+		replDisposeFunc.Release()
+		replExportFunc.Release()
+		replImportFunc.Release()
+		hostRegisterFunc.Release()
+		hostUnregisterFunc.Release()
+		releaseFunc.Release()
+		builtinsFunc.Release()
 		close(done)
 		return nil
 	})
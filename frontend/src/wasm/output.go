@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"syscall/js"
+)
+
+// runOutput collects what puts writes during a run. Everything is kept for
+// the response's output field; when the caller passed an onOutput
+// callback, complete lines are also handed to it in batches each time the
+// run yields to JS, so the page can show them while the run continues.
+type runOutput struct {
+	all      bytes.Buffer
+	pending  bytes.Buffer
+	onOutput js.Value
+}
+
+func newRunOutput(onOutput js.Value) *runOutput {
+	return &runOutput{onOutput: onOutput}
+}
+
+func (o *runOutput) Write(p []byte) (int, error) {
+	o.all.Write(p)
+	if o.streaming() {
+		o.pending.Write(p)
+	}
+	return len(p), nil
+}
+
+// String returns everything written so far
+func (o *runOutput) String() string { return o.all.String() }
+
+func (o *runOutput) streaming() bool {
+	return o.onOutput.Type() == js.TypeFunction
+}
+
+// flush passes the lines completed since the last flush to onOutput as one
+// string. At the end of the run, final also passes a trailing partial
+// line. A callback that throws is dropped rather than failing the run.
+func (o *runOutput) flush(final bool) {
+	if !o.streaming() || o.pending.Len() == 0 {
+		return
+	}
+
+	n := o.pending.Len()
+	if !final {
+		n = bytes.LastIndexByte(o.pending.Bytes(), '\n') + 1
+		if n == 0 {
+			return
+		}
+	}
+	chunk := string(o.pending.Next(n))
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("WASM onOutput callback failed: %v\n", r)
+			o.onOutput = js.Undefined()
+			o.pending.Reset()
+		}
+	}()
+	o.onOutput.Invoke(chunk)
+}
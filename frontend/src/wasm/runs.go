@@ -0,0 +1,212 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"monkey-playground-backend/object"
+)
+
+// defaultSlice is how long a run executes before yielding to the browser
+const defaultSlice = 20 * time.Millisecond
+
+// runOptions is the optional options argument of monkeyExecute and
+// monkeyRepl. Zero values mean no budget and the call's default engine.
+type runOptions struct {
+	engine   string
+	maxSteps int
+	maxDepth int
+	slice    time.Duration
+	onOutput js.Value
+
+	// capabilities names the monkeyHostRegister functions the run may
+	// call with host()
+	capabilities []string
+
+	// prelude names the prelude modules loaded before the code, and
+	// preludeTraces keeps their calls in error traces
+	prelude       []string
+	preludeTraces bool
+
+	// files holds the modules import() resolves against, and the main
+	// program too when the code argument is empty; entry names the main
+	// file (see modules.Program)
+	files map[string]string
+	entry string
+}
+
+// optionsArg reads { engine, maxSteps, maxDepth, sliceMs, onOutput,
+// capabilities, prelude, preludeTraces, files, entry } from args[i], if
+// given
+func optionsArg(args []js.Value, i int) runOptions {
+	opts := runOptions{slice: defaultSlice}
+	if len(args) <= i || args[i].Type() != js.TypeObject {
+		return opts
+	}
+	number := func(name string) int {
+		if v := args[i].Get(name); v.Type() == js.TypeNumber {
+			return v.Int()
+		}
+		return 0
+	}
+	if v := args[i].Get("engine"); v.Type() == js.TypeString {
+		opts.engine = v.String()
+	}
+	if v := args[i].Get("onOutput"); v.Type() == js.TypeFunction {
+		opts.onOutput = v
+	}
+	if v := args[i].Get("entry"); v.Type() == js.TypeString {
+		opts.entry = v.String()
+	}
+	if v := args[i].Get("files"); v.Type() == js.TypeObject {
+		opts.files = map[string]string{}
+		names := js.Global().Get("Object").Call("keys", v)
+		for j := 0; j < names.Length(); j++ {
+			name := names.Index(j).String()
+			if code := v.Get(name); code.Type() == js.TypeString {
+				opts.files[name] = code.String()
+			}
+		}
+	}
+	strings := func(name string) []string {
+		var values []string
+		if v := args[i].Get(name); v.Type() == js.TypeObject {
+			for j := 0; j < v.Length(); j++ {
+				if value := v.Index(j); value.Type() == js.TypeString {
+					values = append(values, value.String())
+				}
+			}
+		}
+		return values
+	}
+	opts.capabilities = strings("capabilities")
+	opts.prelude = strings("prelude")
+	opts.preludeTraces = args[i].Get("preludeTraces").Truthy()
+	opts.maxSteps = number("maxSteps")
+	opts.maxDepth = number("maxDepth")
+	if ms := number("sliceMs"); ms > 0 {
+		opts.slice = time.Duration(ms) * time.Millisecond
+	}
+	return opts
+}
+
+// run is an execution in progress that monkeyCancel can stop
+type run struct {
+	id         int
+	opts       runOptions
+	output     *runOutput
+	cancelled  bool
+	sliceStart time.Time
+}
+
+var (
+	runsMu    sync.Mutex
+	runs      = make(map[int]*run)
+	lastRunID int
+)
+
+// startRun executes work on its own goroutine and returns a Promise for its
+// result, with the run's id as promise.runId. The run yields to the JS
+// event loop every time slice so the page stays responsive and
+// monkeyCancel can reach it. work writes puts output to out, which streams
+// it to the onOutput callback, if any, at each yield.
+func startRun(opts runOptions, work func(limits object.Limits, out *runOutput) any) js.Value {
+	runsMu.Lock()
+	lastRunID++
+	r := &run{id: lastRunID, opts: opts, output: newRunOutput(opts.onOutput)}
+	runs[r.id] = r
+	runsMu.Unlock()
+
+	var executor js.Func
+	executor = js.FuncOf(func(this js.Value, args []js.Value) any {
+		resolve := args[0]
+		go func() {
+			defer r.finish()
+			r.sliceStart = time.Now()
+			result := work(object.Limits{MaxDepth: opts.maxDepth, Monitor: r.monitor}, r.output)
+			r.output.flush(true)
+			resolve.Invoke(result)
+		}()
+		return nil
+	})
+	promise := js.Global().Get("Promise").New(executor)
+	executor.Release()
+
+	promise.Set("runId", r.id)
+	return promise
+}
+
+// resolved returns a Promise already settled with value, for calls that
+// fail before a run starts
+func resolved(value map[string]any) js.Value {
+	return js.Global().Get("Promise").Call("resolve", js.ValueOf(value))
+}
+
+// monitor is the run's object.Limits.Monitor
+func (r *run) monitor(steps int) error {
+	if r.isCancelled() {
+		return errors.New(object.Cancelled)
+	}
+	if r.opts.maxSteps > 0 && steps > r.opts.maxSteps {
+		return fmt.Errorf("%s: more than %d steps", object.BudgetExceeded, r.opts.maxSteps)
+	}
+	if time.Since(r.sliceStart) < r.opts.slice {
+		return nil
+	}
+
+	r.output.flush(false)
+	yieldToJS()
+	r.sliceStart = time.Now()
+	if r.isCancelled() {
+		return errors.New(object.Cancelled)
+	}
+	return nil
+}
+
+func (r *run) isCancelled() bool {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	return r.cancelled
+}
+
+func (r *run) finish() {
+	runsMu.Lock()
+	delete(runs, r.id)
+	runsMu.Unlock()
+}
+
+// yieldToJS parks the run until a zero-delay timeout fires, letting the
+// browser handle input, rendering and monkeyCancel calls in between
+func yieldToJS() {
+	done := make(chan struct{})
+	var resume js.Func
+	resume = js.FuncOf(func(this js.Value, args []js.Value) any {
+		resume.Release()
+		close(done)
+		return nil
+	})
+	js.Global().Call("setTimeout", resume, 0)
+	<-done
+}
+
+// cancel implements monkeyCancel(runId). It returns whether the run was
+// still in progress; its Promise then resolves with an "execution
+// cancelled" error.
+func cancel(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeNumber {
+		return false
+	}
+
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	r, ok := runs[args[0].Int()]
+	if ok {
+		r.cancelled = true
+	}
+	return ok
+}
@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// await waits for promise to resolve and returns its value
+func await(promise js.Value) js.Value {
+	done := make(chan js.Value, 1)
+	then := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- args[0]
+		return nil
+	})
+	defer then.Release()
+	promise.Call("then", then)
+	return <-done
+}
+
+// field returns response[name] as a string, or "" when it is undefined
+func field(response js.Value, name string) string {
+	if v := response.Get(name); !v.IsUndefined() {
+		return v.String()
+	}
+	return ""
+}
+
+func TestExecuteFiles(t *testing.T) {
+	files := map[string]any{
+		"main.monkey":     `let math = import("lib/math"); math["square"](4)`,
+		"lib/math.monkey": "let square = fn(x) { x * x };",
+	}
+	tests := []struct {
+		code    string
+		options map[string]any
+		result  string
+		err     string
+	}{
+		{"", map[string]any{"files": files}, "16", ""},
+		{"", map[string]any{"files": files, "engine": "eval"}, "16", ""},
+		{`import("lib/math")["square"](5)`, map[string]any{"files": map[string]any{"lib/math.monkey": files["lib/math.monkey"]}}, "25", ""},
+		{"1", map[string]any{"files": files}, "", `both code and files["main.monkey"] hold the main program; send it once`},
+		{"", map[string]any{"files": files, "entry": "app.monkey"}, "", "entry file app.monkey is not in files"},
+		{`import("lib/math")`, nil, "", `import "lib/math": no module files available`},
+	}
+
+	for _, tt := range tests {
+		for _, call := range []js.Func{js.FuncOf(execute), js.FuncOf(repl)} {
+			response := await(call.Invoke(tt.code, tt.options))
+			call.Release()
+			if got := field(response, "result"); tt.err == "" && got != tt.result {
+				t.Errorf("%q %v: result %q (error %q), want %q", tt.code, tt.options, got, field(response, "error"), tt.result)
+			}
+			if got := field(response, "error"); got != tt.err {
+				t.Errorf("%q %v: error %q, want %q", tt.code, tt.options, got, tt.err)
+			}
+		}
+	}
+
+	response := await(js.FuncOf(execute).Invoke(`import("lib/missing")`, map[string]any{"files": map[string]any{"lib/math.monkey": files["lib/math.monkey"]}}))
+	if diagnostics := response.Get("diagnostics"); diagnostics.Type() != js.TypeObject || diagnostics.Index(0).Get("file").String() != "main.monkey" {
+		t.Errorf("missing module: diagnostics %v, error %q", diagnostics, field(response, "error"))
+	}
+}
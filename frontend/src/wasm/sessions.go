@@ -0,0 +1,282 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"syscall/js"
+
+	"monkey-playground-backend/lexer"
+	"monkey-playground-backend/object"
+	"monkey-playground-backend/parser"
+	"monkey-playground-backend/session"
+)
+
+// exportVersion is the format version written by monkeyReplExport
+const exportVersion = 1
+
+// replSession is a REPL session owned by the page through its handle. It
+// keeps the inputs it ran so monkeyReplExport can save a transcript that
+// monkeyReplImport replays after a reload.
+type replSession struct {
+	session *session.Session
+	inputs  []string
+	busy    bool
+}
+
+// sessionExport is the JSON written by monkeyReplExport
+type sessionExport struct {
+	Version int      `json:"version"`
+	Engine  string   `json:"engine"`
+	Prelude []string `json:"prelude,omitempty"`
+	Inputs  []string `json:"inputs"`
+}
+
+var (
+	sessionsMu    sync.Mutex
+	sessions      = make(map[int]*replSession)
+	lastSessionID int
+)
+
+// newReplSession registers an empty session on engine (the evaluator when
+// empty, like /api/repl) with the prelude modules named and returns its
+// handle
+func newReplSession(engine string, prelude []string) (int, *replSession, error) {
+	if engine == "" {
+		engine = session.Eval
+	}
+	s, err := session.New(engine, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.LoadPrelude(prelude); err != nil {
+		return 0, nil, err
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	lastSessionID++
+	rs := &replSession{session: s}
+	sessions[lastSessionID] = rs
+	return lastSessionID, rs, nil
+}
+
+// handleArg reads the session handle passed as the only argument
+func handleArg(args []js.Value) (int, bool) {
+	if len(args) != 1 || args[0].Type() != js.TypeNumber {
+		return 0, false
+	}
+	return args[0].Int(), true
+}
+
+// lookupSession returns the session behind a handle
+func lookupSession(handle int) (*replSession, bool) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	rs, ok := sessions[handle]
+	return rs, ok
+}
+
+// acquire marks the session busy, failing if an input is already running
+// in it; inputs must not interleave on shared state
+func (rs *replSession) acquire() bool {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if rs.busy {
+		return false
+	}
+	rs.busy = true
+	return true
+}
+
+func (rs *replSession) release() {
+	sessionsMu.Lock()
+	rs.busy = false
+	sessionsMu.Unlock()
+}
+
+// record adds code to the transcript unless its run was cancelled or out
+// of budget, which a replay could not be expected to finish
+func (rs *replSession) record(code string, response any) {
+	if v, ok := response.(js.Value); ok && (v.Get("cancelled").Truthy() || v.Get("budgetExceeded").Truthy()) {
+		return
+	}
+	sessionsMu.Lock()
+	rs.inputs = append(rs.inputs, code)
+	sessionsMu.Unlock()
+}
+
+// replSessionCode implements monkeyRepl(handle, code, options): it runs
+// code against the session's bindings, ignoring the engine and prelude
+// options. The capabilities and preludeTraces options apply to this input
+// only.
+func replSessionCode(handle int, args []js.Value) js.Value {
+	rs, ok := lookupSession(handle)
+	if !ok {
+		return resolved(map[string]any{
+			"error": fmt.Sprintf("unknown REPL session %d", handle),
+		})
+	}
+	if !rs.acquire() {
+		return resolved(map[string]any{
+			"error": fmt.Sprintf("REPL session %d is busy", handle),
+		})
+	}
+
+	code := args[0].String()
+	opts := optionsArg(args, 1)
+	rs.session.SetHostFunctions(grant(opts.capabilities))
+	rs.session.SetPreludeTraces(opts.preludeTraces)
+	return startRun(opts, func(limits object.Limits, out *runOutput) any {
+		defer rs.release()
+		response := replCode(code, rs.session, out, limits)
+		rs.record(code, response)
+		return response
+	})
+}
+
+// replCreate implements monkeyReplCreate(options), where options may name
+// the engine and the prelude modules. It returns the new session's handle,
+// or { error } for an unknown engine or module.
+func replCreate(this js.Value, args []js.Value) any {
+	opts := optionsArg(args, 0)
+	handle, _, err := newReplSession(opts.engine, opts.prelude)
+	if err != nil {
+		return js.ValueOf(map[string]any{"error": err.Error()})
+	}
+	return handle
+}
+
+// replReset implements monkeyReplReset(handle), dropping every binding and
+// the transcript but keeping the prelude. An input still running finishes
+// against the old state.
+func replReset(this js.Value, args []js.Value) any {
+	handle, ok := handleArg(args)
+	if !ok {
+		return false
+	}
+	rs, ok := lookupSession(handle)
+	if !ok {
+		return false
+	}
+	s, err := session.New(rs.session.Engine(), nil)
+	if err != nil {
+		return false
+	}
+	if err := s.LoadPrelude(rs.session.Prelude()); err != nil {
+		return false
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions[handle] = &replSession{session: s}
+	return true
+}
+
+// replDispose implements monkeyReplDispose(handle)
+func replDispose(this js.Value, args []js.Value) any {
+	handle, ok := handleArg(args)
+	if !ok {
+		return false
+	}
+	return disposeSession(handle)
+}
+
+func disposeSession(handle int) bool {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	_, ok := sessions[handle]
+	delete(sessions, handle)
+	return ok
+}
+
+// replExport implements monkeyReplExport(handle). It returns the session
+// as a JSON string for the page to store, or null for an unknown handle.
+func replExport(this js.Value, args []js.Value) any {
+	handle, ok := handleArg(args)
+	if !ok {
+		return nil
+	}
+	rs, ok := lookupSession(handle)
+	if !ok {
+		return nil
+	}
+
+	sessionsMu.Lock()
+	data := sessionExport{
+		Version: exportVersion,
+		Engine:  rs.session.Engine(),
+		Prelude: rs.session.Prelude(),
+		Inputs:  append([]string{}, rs.inputs...),
+	}
+	sessionsMu.Unlock()
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return string(jsonBytes)
+}
+
+// replImport implements monkeyReplImport(data, options). It creates a
+// session and replays the exported inputs into it, and returns a
+// cancellable Promise for { handle } or { error }. The replay discards
+// output and grants no host functions, so restoring a session does not
+// repeat the side effects its inputs had the first time. Budgets in
+// options apply to each replayed input; capabilities are ignored.
+func replImport(this js.Value, args []js.Value) any {
+	if len(args) < 1 || len(args) > 2 || args[0].Type() != js.TypeString {
+		return resolved(map[string]any{
+			"error": "replImport requires 1 or 2 arguments (exported string, options)",
+		})
+	}
+
+	var data sessionExport
+	if err := json.Unmarshal([]byte(args[0].String()), &data); err != nil {
+		return resolved(map[string]any{
+			"error": fmt.Sprintf("invalid REPL export: %v", err),
+		})
+	}
+	if data.Version != exportVersion {
+		return resolved(map[string]any{
+			"error": fmt.Sprintf("unsupported REPL export version %d", data.Version),
+		})
+	}
+
+	handle, rs, err := newReplSession(data.Engine, data.Prelude)
+	if err != nil {
+		return resolved(map[string]any{"error": err.Error()})
+	}
+	rs.acquire()
+
+	opts := optionsArg(args, 1)
+	rs.session.SetHostFunctions(nil)
+	return startRun(opts, func(limits object.Limits, _ *runOutput) any {
+		defer rs.release()
+		for _, code := range data.Inputs {
+			p := parser.New(lexer.New(code))
+			program := p.ParseProgram()
+			if len(p.Errors()) == 0 {
+				if _, err := rs.session.Run(program, io.Discard, limits); stopped(err) {
+					disposeSession(handle)
+					return js.ValueOf(map[string]any{"error": err.Error()})
+				}
+			}
+			sessionsMu.Lock()
+			rs.inputs = append(rs.inputs, code)
+			sessionsMu.Unlock()
+		}
+		return js.ValueOf(map[string]any{"handle": handle})
+	})
+}
+
+// stopped reports whether err ended a run early through cancellation or a
+// budget rather than through the program itself
+func stopped(err error) bool {
+	return err != nil && (strings.HasPrefix(err.Error(), object.Cancelled) ||
+		strings.HasPrefix(err.Error(), object.BudgetExceeded))
+}
@@ -0,0 +1,42 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+	"testing"
+)
+
+func TestReplImportReplaysQuietly(t *testing.T) {
+	js.Global().Set("hostCalls", 0)
+	hostRegister(js.Null(), []js.Value{js.ValueOf("tick"), jsFunction("globalThis.hostCalls++; return 1")})
+	defer hostUnregister(js.Null(), []js.Value{js.ValueOf("tick")})
+
+	data, err := json.Marshal(sessionExport{
+		Version: exportVersion,
+		Engine:  "vm",
+		Inputs:  []string{"let a = 1;", "let = ;", `puts("again"); host("tick")`, "let b = a + 1;"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := js.ValueOf(map[string]any{"capabilities": []any{"tick"}})
+	response := await(replImport(js.Null(), []js.Value{js.ValueOf(string(data)), options}).(js.Value))
+	if msg := field(response, "error"); msg != "" {
+		t.Fatalf("import failed: %s", msg)
+	}
+	handle := response.Get("handle").Int()
+	defer disposeSession(handle)
+
+	if calls := js.Global().Get("hostCalls").Int(); calls != 0 {
+		t.Errorf("replay called the host %d times, want 0", calls)
+	}
+	result := await(replSessionCode(handle, []js.Value{js.ValueOf("b")}))
+	if got := field(result, "result"); got != "2" {
+		t.Errorf("b = %q after the parse error, want 2 (error %q)", got, field(result, "error"))
+	}
+	if got := field(result, "output"); got != "" {
+		t.Errorf("output %q, want the replayed puts discarded", got)
+	}
+}
@@ -0,0 +1,261 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+	"syscall/js"
+
+	"monkey-playground-backend/object"
+)
+
+// maxSafeInteger is Number.MAX_SAFE_INTEGER, the largest integer a JS
+// number holds exactly
+const maxSafeInteger = 1<<53 - 1
+
+// functionHandles keeps the Monkey functions handed to JavaScript, so a
+// handle passed back to host() or a later run resolves to the function
+var (
+	functionHandlesMu  sync.Mutex
+	functionHandles    = make(map[int]object.Object)
+	functionHandleIDs  = make(map[object.Object]int)
+	lastFunctionHandle int
+)
+
+// handleKey is the symbol under which a function handle stores its id;
+// being a symbol, it cannot clash with keys of objects the page builds
+func handleKey() js.Value {
+	return js.Global().Get("Symbol").Call("for", "monkey.function")
+}
+
+// toJS converts a Monkey value to JavaScript without a JSON round trip.
+// Integers, booleans and strings map to their JS counterparts and null to
+// null; integers past Number.MAX_SAFE_INTEGER become BigInts, so they
+// arrive exact. Arrays become arrays. Hashes with only string keys become plain
+// objects, and any other hash a Map keyed by the converted keys, so 1 and
+// "1" stay distinct. Functions become opaque frozen handles of the form
+// { type: "function", inspect } that convert back to the same function.
+func toJS(obj object.Object) (js.Value, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return js.Null(), nil
+	case *object.Integer:
+		if obj.Big != nil {
+			return js.Global().Get("BigInt").Invoke(obj.Big.String()), nil
+		}
+		if obj.Value > maxSafeInteger || obj.Value < -maxSafeInteger {
+			return js.Global().Get("BigInt").Invoke(strconv.FormatInt(obj.Value, 10)), nil
+		}
+		return js.ValueOf(obj.Value), nil
+	case *object.Boolean:
+		return js.ValueOf(obj.Value), nil
+	case *object.String:
+		return js.ValueOf(obj.Value), nil
+	case *object.Array:
+		array := js.Global().Get("Array").New(len(obj.Elements))
+		for i, element := range obj.Elements {
+			v, err := toJS(element)
+			if err != nil {
+				return js.Undefined(), err
+			}
+			array.SetIndex(i, v)
+		}
+		return array, nil
+	case *object.Hash:
+		return hashToJS(obj)
+	case *object.Function, *object.Closure, *object.CompiledFunction, *object.Builtin:
+		return functionHandle(obj), nil
+	default:
+		return js.Undefined(), fmt.Errorf("cannot pass %s to JavaScript", obj.Type())
+	}
+}
+
+func hashToJS(hash *object.Hash) (js.Value, error) {
+	pairs := hash.Ordered()
+	stringKeys := true
+	for _, pair := range pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			stringKeys = false
+		}
+	}
+
+	var result js.Value
+	if stringKeys {
+		result = js.Global().Get("Object").New()
+	} else {
+		result = js.Global().Get("Map").New()
+	}
+	for _, pair := range pairs {
+		value, err := toJS(pair.Value)
+		if err != nil {
+			return js.Undefined(), err
+		}
+		if stringKeys {
+			result.Set(pair.Key.(*object.String).Value, value)
+			continue
+		}
+		key, err := toJS(pair.Key)
+		if err != nil {
+			return js.Undefined(), err
+		}
+		result.Call("set", key, value)
+	}
+	return result, nil
+}
+
+// functionHandle returns the handle for fn, reusing the one fn was given
+// before
+func functionHandle(fn object.Object) js.Value {
+	functionHandlesMu.Lock()
+	id, ok := functionHandleIDs[fn]
+	if !ok {
+		lastFunctionHandle++
+		id = lastFunctionHandle
+		functionHandles[id] = fn
+		functionHandleIDs[fn] = id
+	}
+	functionHandlesMu.Unlock()
+
+	handle := js.ValueOf(map[string]any{"type": "function", "inspect": fn.Inspect()})
+	js.Global().Get("Reflect").Call("set", handle, handleKey(), id)
+	return js.Global().Get("Object").Call("freeze", handle)
+}
+
+// handleID returns the id stored in a function handle
+func handleID(v js.Value) (int, bool) {
+	if v.Type() != js.TypeObject {
+		return 0, false
+	}
+	id := js.Global().Get("Reflect").Call("get", v, handleKey())
+	if id.Type() != js.TypeNumber {
+		return 0, false
+	}
+	return id.Int(), true
+}
+
+// releaseHandle implements monkeyRelease(handle): it forgets a function
+// handle so the function can be garbage collected. The handle no longer
+// converts back afterwards.
+func releaseHandle(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return false
+	}
+	id, ok := handleID(args[0])
+	if !ok {
+		return false
+	}
+	functionHandlesMu.Lock()
+	defer functionHandlesMu.Unlock()
+	fn, ok := functionHandles[id]
+	delete(functionHandles, id)
+	delete(functionHandleIDs, fn)
+	return ok
+}
+
+// typeOf is JavaScript's typeof operator; syscall/js has no Type for
+// BigInts and panics on them
+var typeOf = js.Global().Get("Function").New("v", "return typeof v")
+
+// fromJS converts a JavaScript value to Monkey, the inverse of toJS:
+// whole numbers and BigInts become integers (big ones past int64), arrays
+// become arrays, Maps and other objects become hashes, function handles
+// become their functions and undefined becomes null. A nil result stands for null.
+func fromJS(v js.Value) (object.Object, error) {
+	if typeOf.Invoke(v).String() == "bigint" {
+		text := js.Global().Get("String").Invoke(v).String()
+		n, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert BigInt %s", text)
+		}
+		return object.NewBigInteger(n), nil
+	}
+	switch v.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		return nil, nil
+	case js.TypeBoolean:
+		return &object.Boolean{Value: v.Bool()}, nil
+	case js.TypeString:
+		return &object.String{Value: v.String()}, nil
+	case js.TypeNumber:
+		f := v.Float()
+		if math.IsInf(f, 0) || f != math.Trunc(f) {
+			return nil, fmt.Errorf("%v is not an integer", f)
+		}
+		if f >= -(1<<63) && f < 1<<63 {
+			return &object.Integer{Value: int64(f)}, nil
+		}
+		n, _ := big.NewFloat(f).Int(nil)
+		return object.NewBigInteger(n), nil
+	case js.TypeObject:
+		if id, ok := handleID(v); ok {
+			functionHandlesMu.Lock()
+			fn, ok := functionHandles[id]
+			functionHandlesMu.Unlock()
+			if !ok {
+				return nil, fmt.Errorf("function handle %d was released", id)
+			}
+			return fn, nil
+		}
+		if js.Global().Get("Array").Call("isArray", v).Bool() {
+			elements := make([]object.Object, v.Length())
+			for i := range elements {
+				element, err := fromJS(v.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				elements[i] = orNull(element)
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+		if v.InstanceOf(js.Global().Get("Map")) {
+			return mapFromJS(v)
+		}
+		keys := js.Global().Get("Object").Call("keys", v)
+		hash := object.NewHash()
+		for i := 0; i < keys.Length(); i++ {
+			key := &object.String{Value: keys.Index(i).String()}
+			value, err := fromJS(v.Get(key.Value))
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: orNull(value)})
+		}
+		return hash, nil
+	default:
+		return nil, fmt.Errorf("cannot convert a JavaScript %s", v.Type())
+	}
+}
+
+func mapFromJS(m js.Value) (object.Object, error) {
+	entries := js.Global().Get("Array").Call("from", m.Call("entries"))
+	hash := object.NewHash()
+	for i := 0; i < entries.Length(); i++ {
+		key, err := fromJS(entries.Index(i).Index(0))
+		if err != nil {
+			return nil, err
+		}
+		hashKey, ok := object.HashKeyOf(key)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", orNull(key).Type())
+		}
+		value, err := fromJS(entries.Index(i).Index(1))
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(hashKey, object.HashPair{Key: key, Value: orNull(value)})
+	}
+	return hash, nil
+}
+
+// orNull stores a converted null inside arrays and hashes, where the
+// engines expect a Null object rather than nil
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return &object.Null{}
+	}
+	return obj
+}